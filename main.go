@@ -2,246 +2,4450 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
+	"container/list"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
 )
 
-var httpClient = &http.Client{
-	Timeout: 10 * time.Second,
+// Build version of this server, set at build time with
+// -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// Timeout for ABS API calls when ABS_HTTP_TIMEOUT is unset or invalid
+const defaultHTTPTimeout = 10 * time.Second
+
+// Connection pool defaults, sized for a single ABS host
+const (
+	defaultMaxIdleConns        = 20
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// Settings for the HTTP client used for ABS API calls
+type httpClientConfig struct {
+	timeout             time.Duration
+	insecureSkipVerify  bool
+	caCertFile          string
+	clientCertFile      string
+	clientKeyFile       string
+	proxyURL            string
+	noProxy             string
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
 }
 
-func getEnvOrParam(paramValue, envKey string) string {
-	if paramValue != "" {
-		return paramValue
+var (
+	clientConfig = httpClientConfigFromEnv()
+
+	// Replaced in main with a client built from clientConfig
+	httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+
+	// Headers from ABS_EXTRA_HEADERS sent with every request, set in main
+	extraHeaders map[string]string
+
+	// HTTP Basic credentials for a reverse proxy in front of ABS, separate from the ABS token
+	basicAuthUser = os.Getenv("ABS_BASIC_AUTH_USER")
+	basicAuthPass = os.Getenv("ABS_BASIC_AUTH_PASS")
+
+	// Client for calls made with insecure=true, built on first use
+	insecureClient     *http.Client
+	insecureClientErr  error
+	insecureClientOnce sync.Once
+)
+
+// Helper to read the HTTP client settings from the environment
+func httpClientConfigFromEnv() httpClientConfig {
+	insecure, _ := strconv.ParseBool(os.Getenv("ABS_INSECURE_SKIP_VERIFY"))
+	return httpClientConfig{
+		timeout:             parseTimeout(os.Getenv("ABS_HTTP_TIMEOUT"), defaultHTTPTimeout),
+		insecureSkipVerify:  insecure,
+		caCertFile:          os.Getenv("ABS_CA_CERT_FILE"),
+		clientCertFile:      os.Getenv("ABS_CLIENT_CERT_FILE"),
+		clientKeyFile:       os.Getenv("ABS_CLIENT_KEY_FILE"),
+		proxyURL:            os.Getenv("ABS_HTTP_PROXY"),
+		noProxy:             getEnvAny("NO_PROXY", "no_proxy"),
+		maxIdleConns:        parsePositiveInt(os.Getenv("ABS_MAX_IDLE_CONNS"), defaultMaxIdleConns),
+		maxIdleConnsPerHost: parsePositiveInt(os.Getenv("ABS_MAX_IDLE_CONNS_PER_HOST"), defaultMaxIdleConnsPerHost),
+		idleConnTimeout:     parseTimeout(os.Getenv("ABS_IDLE_CONN_TIMEOUT"), defaultIdleConnTimeout),
 	}
-	return os.Getenv(envKey)
 }
 
-func getABSConfig(request mcp.CallToolRequest) (baseURL, token string, err error) {
-	baseURLParam := request.GetString("base_url", "")
-	tokenParam := request.GetString("token", "")
+// Helper to build the HTTP client used for ABS API calls
+func newHTTPClient(cfg httpClientConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.maxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.maxIdleConns
+	}
+	if cfg.maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.maxIdleConnsPerHost
+	}
+	if cfg.idleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.idleConnTimeout
+	}
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
 
-	baseURL = getEnvOrParam(baseURLParam, "ABS_BASE_URL")
-	token = getEnvOrParam(tokenParam, "ABS_API_KEY")
+	proxy, err := proxyFunc(cfg.proxyURL, cfg.noProxy)
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = proxy
 
-	if baseURL == "" {
-		return "", "", fmt.Errorf("base_url parameter or ABS_BASE_URL environment variable is required")
+	if cfg.caCertFile != "" {
+		pool, err := loadCACertPool(cfg.caCertFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.RootCAs = pool
 	}
-	if token == "" {
-		return "", "", fmt.Errorf("token parameter or ABS_API_KEY environment variable is required")
+
+	if cfg.clientCertFile != "" || cfg.clientKeyFile != "" {
+		cert, err := loadClientCertificate(cfg.clientCertFile, cfg.clientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
 	}
 
-	// Always append /api to the base URL
-	baseURL = fmt.Sprintf("%s/api", baseURL)
+	if cfg.insecureSkipVerify {
+		logger.Warn("TLS certificate verification is disabled for Audiobookshelf requests")
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
 
-	return baseURL, token, nil
+	return &http.Client{
+		Timeout:   cfg.timeout,
+		Transport: transport,
+	}, nil
 }
 
-// Helper to add base_url and token parameters to a tool
-func withABSAuth() []mcp.ToolOption {
-	return []mcp.ToolOption{
-		mcp.WithString("base_url",
-			mcp.Description("Audiobookshelf base API URL, e.g. https://abs.example.com/api (defaults to ABS_BASE_URL env var)"),
-		),
-		mcp.WithString("token",
-			mcp.Description("Bearer token used to authenticate with Audiobookshelf (defaults to ABS_API_KEY env var)"),
-		),
+// Helper to parse "Key: Value" pairs separated by commas or newlines, such as
+// the headers a reverse proxy needs in front of ABS
+func parseExtraHeaders(value string) (map[string]string, error) {
+	headers := map[string]string{}
+	entries := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, headerValue, found := strings.Cut(entry, ":")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("ABS_EXTRA_HEADERS entry %q must be in the form Key: Value", entry)
+		}
+		headers[http.CanonicalHeaderKey(key)] = strings.TrimSpace(headerValue)
 	}
+
+	return headers, nil
 }
 
-// Helper to create a simple list/get tool pair
-func createSimpleGETHandler(path string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// Helper to choose how requests are proxied: the standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+// environment by default, or every request through proxy except NO_PROXY hosts
+func proxyFunc(proxy, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("ABS_HTTP_PROXY must be a proxy URL such as http://proxy.example.com:3128")
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if proxyBypassed(req.URL.Host, noProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// Helper to check a request host against a NO_PROXY list of hosts, domain
+// suffixes, host:port pairs, CIDR ranges, or "*"; loopback is never proxied
+func proxyBypassed(hostport, noProxy string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	ip := net.ParseIP(host)
+	if host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+
+	for _, entry := range splitCommaList(strings.ToLower(noProxy)) {
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if _, _, err := net.SplitHostPort(entry); err == nil {
+			if entry == strings.ToLower(hostport) {
+				return true
+			}
+			continue
+		}
+
+		domain := strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper to build a cert pool of the system roots plus the PEM certificates in path
+func loadCACertPool(path string) (*x509.CertPool, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ABS_CA_CERT_FILE: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("ABS_CA_CERT_FILE %s contains no valid PEM certificates", path)
+	}
+
+	return pool, nil
+}
+
+// Helper to load the client certificate presented to servers that require mutual TLS
+func loadClientCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	if certFile == "" || keyFile == "" {
+		return tls.Certificate{}, fmt.Errorf("ABS_CLIENT_CERT_FILE and ABS_CLIENT_KEY_FILE must be set together")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("load client certificate (check that ABS_CLIENT_KEY_FILE is the key for ABS_CLIENT_CERT_FILE): %w", err)
+	}
+
+	return cert, nil
+}
+
+// Helper to get the client that skips TLS verification, leaving the default
+// client untouched
+func insecureHTTPClient() (*http.Client, error) {
+	insecureClientOnce.Do(func() {
+		cfg := clientConfig
+		cfg.insecureSkipVerify = true
+		insecureClient, insecureClientErr = newHTTPClient(cfg)
+	})
+	return insecureClient, insecureClientErr
+}
+
+// Helper to parse a positive integer, falling back when the value is empty,
+// invalid, or not positive
+func parsePositiveInt(value string, fallback int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+// Helper to parse a retry count, falling back when the value is empty, invalid,
+// or negative; 0 disables retries
+func parseMaxRetries(value string, fallback int) int {
+	retries, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || retries < 0 {
+		return fallback
+	}
+	return retries
+}
+
+// Helper to parse a timeout given as a Go duration ("90s", "2m") or whole
+// seconds, falling back when the value is empty, invalid, or not positive
+func parseTimeout(value string, fallback time.Duration) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return fallback
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+		return timeout
+	}
+	return fallback
+}
+
+// Number of times a failed ABS API call is retried when ABS_MAX_RETRIES is unset or invalid
+const defaultMaxRetries = 2
+
+var maxRetries = parseMaxRetries(os.Getenv("ABS_MAX_RETRIES"), defaultMaxRetries)
+
+// Delay before the first retry; each further retry doubles it
+var retryBaseDelay = 500 * time.Millisecond
+
+// Whether POST and PATCH calls are retried too, which may repeat their side effects
+var retryNonIdempotent, _ = strconv.ParseBool(os.Getenv("ABS_RETRY_NON_IDEMPOTENT"))
+
+// How often, and for how long, long-running tools poll ABS for task progress
+const (
+	defaultProgressInterval = 2 * time.Second
+	defaultProgressTimeout  = 5 * time.Minute
+)
+
+// ABS can answer a scan request before the task is listed, so a task that
+// hasn't shown up yet only counts as done after this many polls
+const taskStartPolls = 3
+
+var (
+	progressInterval = parseTimeout(os.Getenv("ABS_PROGRESS_INTERVAL"), defaultProgressInterval)
+	progressTimeout  = parseTimeout(os.Getenv("ABS_PROGRESS_TIMEOUT"), defaultProgressTimeout)
+)
+
+// Largest tool result, in bytes, returned without truncation when max_bytes
+// isn't given; 0 means no limit
+var maxResponseBytes = parsePositiveInt(os.Getenv("ABS_MAX_RESPONSE_BYTES"), 0)
+
+// Whether tool results include the ABS response as structured content by default
+var structuredOutput, _ = strconv.ParseBool(os.Getenv("ABS_STRUCTURED_OUTPUT"))
+
+// Per-request connection settings taken from the withABSAuth parameters
+type requestOptions struct {
+	timeout     time.Duration
+	maxRetries  *int
+	insecure    bool
+	authInQuery bool
+	noCache     bool
+}
+
+// Leveled logger for diagnostics. It always writes to stderr, since stdout
+// carries the MCP framing over stdio, and only emits messages at or above
+// ABS_LOG_LEVEL (debug, info, warn, or error; info by default).
+var logger = newLogger(os.Stderr, os.Getenv("ABS_LOG_LEVEL"))
+
+// Whether each ABS request and its response are logged, from ABS_DEBUG
+var debugRequests, _ = strconv.ParseBool(os.Getenv("ABS_DEBUG"))
+
+// Request bodies longer than this are cut short in ABS_DEBUG logs
+const debugBodyLimit = 1024
+
+// Helper to build the leveled logger, passing every message and attribute
+// through redactSecrets so logs are safe to share
+func newLogger(w io.Writer, level string) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level:       parseLogLevel(level),
+		ReplaceAttr: redactAttr,
+	}))
+}
+
+// Patterns for credentials that may turn up in URLs, headers, and error
+// messages, with the replacement that masks each
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// Authorization and Proxy-Authorization header values
+	{regexp.MustCompile(`(?i)(\b(?:proxy-)?authorization:\s*(?:bearer|basic)\s+)\S+`), "${1}[REDACTED]"},
+	// Bearer tokens quoted without their header
+	{regexp.MustCompile(`(\bBearer\s+)[A-Za-z0-9._~+/=-]+`), "${1}[REDACTED]"},
+	// token and similar query parameters, as added by auth_in_query
+	{regexp.MustCompile(`(?i)([?&](?:token|access_token|api_key|apikey)=)[^&\s"']+`), "${1}[REDACTED]"},
+	// Passwords in URL userinfo
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`), "${1}[REDACTED]@"},
+}
+
+// Matches a JSON string field, such as one echoed back in an ABS error body
+var jsonStringField = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// Helper to mask Authorization header values, bearer tokens, token query
+// parameters, passwords in URLs, and credential fields in JSON, as judged by
+// isSensitiveField
+func redactSecrets(s string) string {
+	for _, secret := range secretPatterns {
+		s = secret.pattern.ReplaceAllString(s, secret.replacement)
+	}
+	return jsonStringField.ReplaceAllStringFunc(s, func(field string) string {
+		match := jsonStringField.FindStringSubmatch(field)
+		if !isSensitiveField(match[1]) {
+			return field
+		}
+		return `"` + match[1] + `"` + match[2] + `"[REDACTED]"`
+	})
+}
+
+// Helper for slog's ReplaceAttr option to mask secrets in string and error
+// values, including the message itself
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	switch value := attr.Value.Any().(type) {
+	case string:
+		attr.Value = slog.StringValue(redactSecrets(value))
+	case error:
+		attr.Value = slog.StringValue(redactSecrets(value.Error()))
+	}
+	return attr
+}
+
+// redactedError masks secrets in an error's message while keeping it
+// available to errors.Is and errors.As
+type redactedError struct {
+	err error
+}
+
+func (e redactedError) Error() string { return redactSecrets(e.err.Error()) }
+func (e redactedError) Unwrap() error { return e.err }
+
+// Helper to parse a log level name, falling back to info when it's empty or
+// unrecognized
+func parseLogLevel(value string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// withToolLogging is tool handler middleware that logs each tool call at
+// debug level, and any failure, including error results, at error level
+func withToolLogging(next server.ToolHandlerFunc) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		baseURL, token, err := getABSConfig(request)
+		tool := request.Params.Name
+		logger.DebugContext(ctx, "tool call", "tool", tool)
+		start := time.Now()
+
+		result, err := next(ctx, request)
+		elapsed := time.Since(start)
+		switch {
+		case err != nil:
+			logger.ErrorContext(ctx, "tool call failed", "tool", tool, "duration", elapsed, "error", err)
+		case result != nil && result.IsError:
+			logger.ErrorContext(ctx, "tool call failed", "tool", tool, "duration", elapsed, "error", resultErrorText(result))
+		default:
+			logger.DebugContext(ctx, "tool call finished", "tool", tool, "duration", elapsed)
+		}
+		return result, err
+	}
+}
+
+// Helper to get the message from an error result's text content
+func resultErrorText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+type requestOptionsKey struct{}
+
+// withRequestOptions is tool handler middleware that reads the per-request
+// connection parameters and passes them to the shared request path via ctx.
+// A profile parameter also brings that profile's timeout and max_retries,
+// below the timeout_seconds and max_retries parameters and the environment,
+// as for base_url and token. The timeout deadline is derived from the
+// incoming ctx, so the call still ends early if the client cancels it.
+func withRequestOptions(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Without a profile parameter the startup profile's settings, applied
+		// by applyConfigFile, are already in effect. An unknown profile is
+		// reported by getABSConfig.
+		var profile configProfile
+		if request.GetString("profile", "") != "" {
+			profile, _ = fileSettingsForRequest(request)
+		}
+
+		var opts requestOptions
+		if seconds := request.GetFloat("timeout_seconds", 0); seconds > 0 {
+			opts.timeout = time.Duration(seconds * float64(time.Second))
+		} else if os.Getenv("ABS_HTTP_TIMEOUT") == "" && profile.Timeout != "" {
+			opts.timeout = parseTimeout(profile.Timeout, 0)
+		}
+		if opts.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+			defer cancel()
+		}
+
+		if _, ok := request.GetArguments()["max_retries"]; ok {
+			if retries := request.GetInt("max_retries", 0); retries >= 0 {
+				opts.maxRetries = &retries
+			}
+		} else if os.Getenv("ABS_MAX_RETRIES") == "" && profile.MaxRetries != nil && *profile.MaxRetries >= 0 {
+			retries := *profile.MaxRetries
+			opts.maxRetries = &retries
+		}
+		opts.insecure = request.GetBool("insecure", false)
+		opts.authInQuery = request.GetBool("auth_in_query", false)
+		opts.noCache = request.GetBool("no_cache", false)
+		return next(context.WithValue(ctx, requestOptionsKey{}, opts), request)
+	}
+}
+
+// withStructuredContent is tool handler middleware that, when the structured
+// parameter or ABS_STRUCTURED_OUTPUT asks for it, also returns a text result
+// holding a JSON object as structured content. Anything else, such as plain
+// text, binary downloads, or invalid JSON, is returned unchanged.
+func withStructuredContent(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil || !request.GetBool("structured", structuredOutput) {
+			return result, err
+		}
+		return structuredResult(result), nil
+	}
+}
+
+// withResponseLimit is tool handler middleware that cuts text results down to
+// max_bytes, or ABS_MAX_RESPONSE_BYTES by default, and says how much was left
+// out, so multi-megabyte responses don't overwhelm the client. It runs after
+// any structured content is attached, which is dropped when it's over the
+// limit too, and a cut result is marked with a truncated flag in _meta.
+func withResponseLimit(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		limit := request.GetInt("max_bytes", maxResponseBytes)
+		if err != nil || result == nil || limit <= 0 {
+			return result, err
+		}
+
+		truncated := false
+		for i, content := range result.Content {
+			text, ok := mcp.AsTextContent(content)
+			if !ok || len(text.Text) <= limit {
+				continue
+			}
+			text.Text = truncateText(text.Text, limit)
+			result.Content[i] = *text
+			truncated = true
+		}
+		if result.StructuredContent != nil {
+			if encoded, err := json.Marshal(result.StructuredContent); err != nil || len(encoded) > limit {
+				result.StructuredContent = nil
+				truncated = true
+			}
+		}
+		if truncated {
+			if result.Meta == nil {
+				result.Meta = &mcp.Meta{}
+			}
+			if result.Meta.AdditionalFields == nil {
+				result.Meta.AdditionalFields = map[string]any{}
+			}
+			result.Meta.AdditionalFields["truncated"] = true
+		}
+		return result, nil
+	}
+}
+
+// Helper to cut text to at most limit bytes, without splitting a UTF-8
+// character, followed by a notice of how many bytes were omitted
+func truncateText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n\n[Response truncated: showing the first %d of %d bytes, %d bytes omitted. Set max_bytes to a larger value to see more.]",
+		text[:cut], cut, len(text), len(text)-cut)
+}
+
+// withSummary wraps a listing tool's handler so that, when summarize is set,
+// the list under listKey is cut down to a count and the given fields of each
+// entry. Responses of any other shape are returned in full.
+func withSummary(listKey string, fields []string, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError || len(result.Content) != 1 || !request.GetBool("summarize", false) {
+			return result, err
+		}
+
+		text, ok := mcp.AsTextContent(result.Content[0])
+		if !ok {
+			return result, nil
+		}
+		summary, ok := summarizeList([]byte(text.Text), listKey, fields)
+		if !ok {
+			return result, nil
+		}
+		return mcp.NewToolResultText(string(summary)), nil
+	}
+}
+
+// Helper to reduce a {"<listKey>": [...]} response to its count, any total
+// reported by a paginated endpoint, and the given fields of each entry
+func summarizeList(body []byte, listKey string, fields []string) ([]byte, bool) {
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, false
+	}
+	list, ok := response[listKey].([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	entries := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		entry := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			if value, ok := object[field]; ok {
+				entry[field] = value
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	summary := map[string]interface{}{
+		"count": len(entries),
+		listKey: entries,
+	}
+	if total, ok := response["total"]; ok {
+		summary["total"] = total
+	}
+
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return nil, false
+	}
+	return encoded, true
+}
+
+// withCSVOutput wraps a listing tool's handler so that output=csv flattens the
+// list under listKey into CSV rows, keeping any structured content. Responses
+// of any other shape are returned as JSON.
+func withCSVOutput(listKey string, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		output := strings.ToLower(request.GetString("output", "json"))
+		if output == "json" {
+			return next(ctx, request)
+		}
+		if output != "csv" {
+			return mcp.NewToolResultError(fmt.Sprintf("unsupported output %q; expected json or csv", output)), nil
+		}
+
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError || len(result.Content) != 1 {
+			return result, err
+		}
+		text, ok := mcp.AsTextContent(result.Content[0])
+		if !ok {
+			return result, nil
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(text.Text))
+		decoder.UseNumber()
+		var response map[string]interface{}
+		if err := decoder.Decode(&response); err != nil {
+			return result, nil
+		}
+		list, ok := response[listKey].([]interface{})
+		if !ok {
+			return result, nil
+		}
+
+		rows, err := jsonArrayToCSV(list)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		// Keep any structured content, which tools with an output schema must return
+		csvResult := mcp.NewToolResultText(rows)
+		csvResult.StructuredContent = result.StructuredContent
+		return csvResult, nil
+	}
+}
 
-		body, err := absGET(ctx, baseURL, token, path)
+// jsonArrayToCSV flattens a decoded JSON array into CSV. Objects become one
+// row each, with a column per flat field in name order; nested objects and
+// arrays are left out. Arrays of plain values become a single value column.
+func jsonArrayToCSV(list []interface{}) (string, error) {
+	var columns []string
+	seen := map[string]bool{}
+	for _, item := range list {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range object {
+			if !seen[key] && isFlatJSONValue(value) {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+	if len(columns) == 0 {
+		columns = []string{"value"}
+		writer.Write(columns)
+		for _, item := range list {
+			if !isFlatJSONValue(item) {
+				continue
+			}
+			writer.Write([]string{csvCell(item)})
+		}
+	} else {
+		writer.Write(columns)
+		for _, item := range list {
+			object, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				if value := object[column]; isFlatJSONValue(value) {
+					row[i] = csvCell(value)
+				}
+			}
+			writer.Write(row)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("write CSV: %w", err)
+	}
+	return out.String(), nil
+}
+
+// Helper to report whether a decoded JSON value fits in a single CSV cell
+func isFlatJSONValue(value interface{}) bool {
+	switch value.(type) {
+	case string, json.Number, float64, bool, nil:
+		return true
+	default:
+		return false
+	}
+}
+
+// Helper to render a flat JSON value as a CSV cell; null becomes empty
+func csvCell(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// Response fields that hold a number of seconds
+var secondsFields = map[string]bool{
+	"currentTime":   true,
+	"duration":      true,
+	"startTime":     true,
+	"timeListening": true,
+	"today":         true,
+	"totalTime":     true,
+}
+
+// withHumanizedDurations wraps a handler so that, when humanize is set, each
+// seconds field in its JSON response gets a readable <field>Human sibling
+// such as "1h 23m". The raw values are kept.
+func withHumanizedDurations(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError || len(result.Content) != 1 || !request.GetBool("humanize", false) {
+			return result, err
+		}
+
+		text, ok := mcp.AsTextContent(result.Content[0])
+		if !ok {
+			return result, nil
+		}
+		var response interface{}
+		if err := json.Unmarshal([]byte(text.Text), &response); err != nil {
+			return result, nil
+		}
+
+		humanized, err := json.Marshal(humanizeDurations(response))
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return result, nil
+		}
+		return mcp.NewToolResultText(string(humanized)), nil
+	}
+}
+
+// Helper to add a <field>Human entry next to every seconds field in a
+// decoded JSON value, at any depth
+func humanizeDurations(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		human := map[string]string{}
+		for key, field := range v {
+			if seconds, ok := field.(float64); ok && secondsFields[key] {
+				human[key+"Human"] = formatSeconds(seconds)
+			} else {
+				v[key] = humanizeDurations(field)
+			}
+		}
+		for key, formatted := range human {
+			v[key] = formatted
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = humanizeDurations(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// formatSeconds renders a number of seconds as a short readable duration,
+// such as "1h 23m", "4m 5s", or "0s"
+func formatSeconds(seconds float64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	total := int64(seconds + 0.5)
+	hours, minutes, secs := total/3600, total%3600/60, total%60
+
+	switch {
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, secs)
+	default:
+		return fmt.Sprintf("%ds", secs)
+	}
+}
+
+// withPrettyJSON is tool handler middleware that re-indents JSON text results
+// when the pretty parameter is set. Text that isn't valid JSON is returned
+// unchanged.
+func withPrettyJSON(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil || result == nil || !request.GetBool("pretty", false) {
+			return result, err
+		}
+
+		for i, content := range result.Content {
+			text, ok := mcp.AsTextContent(content)
+			if !ok {
+				continue
+			}
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, []byte(text.Text), "", "  "); err != nil {
+				continue
+			}
+			text.Text = indented.String()
+			result.Content[i] = *text
+		}
+		return result, nil
+	}
+}
+
+// withStructuredResult wraps the handler of a tool that declares an output
+// schema so its JSON results always carry structured content, regardless of
+// the structured parameter. Arrays and other non-object values, such as the
+// library's personalized shelves, are wrapped as {"results": ...}.
+func withStructuredResult(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil {
+			return result, err
+		}
+		result = structuredResult(result)
+		if result == nil || result.IsError || result.StructuredContent != nil || len(result.Content) != 1 {
+			return result, nil
+		}
+		text, ok := mcp.AsTextContent(result.Content[0])
+		if !ok {
+			return result, nil
+		}
+		var value any
+		if err := json.Unmarshal([]byte(text.Text), &value); err == nil && value != nil {
+			result.StructuredContent = map[string]any{"results": value}
+		}
+		return result, nil
+	}
+}
+
+// Output schema for the libraries tool, covering the fields clients rely on
+var librariesOutputSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"libraries": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"id": {"type": "string"},
+					"name": {"type": "string"},
+					"mediaType": {"type": "string", "enum": ["book", "podcast"]},
+					"folders": {"type": "array", "items": {"type": "object"}}
+				},
+				"required": ["id", "name", "mediaType"]
+			}
+		}
+	},
+	"required": ["libraries"]
+}`)
+
+// Output schema for the library tool. Its shape depends on the sub-resource
+// requested, so only the top level is declared.
+var libraryOutputSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"id": {"type": "string"},
+		"name": {"type": "string"},
+		"mediaType": {"type": "string", "enum": ["book", "podcast"]},
+		"results": {"type": "array", "description": "Sub-resource results, or the whole response when ABS returns an array, as for personalized"},
+		"total": {"type": "number"}
+	}
+}`)
+
+// Helper to attach the JSON object in a single-text result as structured
+// content. MCP requires structured content to be an object, so other JSON
+// values are left as text.
+func structuredResult(result *mcp.CallToolResult) *mcp.CallToolResult {
+	if result == nil || result.IsError || result.StructuredContent != nil || len(result.Content) != 1 {
+		return result
+	}
+	text, ok := mcp.AsTextContent(result.Content[0])
+	if !ok {
+		return result
+	}
+
+	var structured map[string]any
+	if err := json.Unmarshal([]byte(text.Text), &structured); err != nil || structured == nil {
+		return result
+	}
+	result.StructuredContent = structured
+	return result
+}
+
+// Helper to read the per-request connection settings, if any, from ctx
+func requestOptionsFromContext(ctx context.Context) requestOptions {
+	opts, _ := ctx.Value(requestOptionsKey{}).(requestOptions)
+	return opts
+}
+
+// Helper to pick the retry count for a request, preferring max_retries over ABS_MAX_RETRIES
+func maxRetriesForRequest(ctx context.Context) int {
+	if opts := requestOptionsFromContext(ctx); opts.maxRetries != nil {
+		return *opts.maxRetries
+	}
+	return maxRetries
+}
+
+// Helper to pick the HTTP client for a request, applying any per-request TLS
+// verification setting. Calls with their own timeout_seconds deadline on ctx
+// get a client without the global timeout so they can run longer than it.
+func clientForRequest(ctx context.Context) (*http.Client, error) {
+	opts := requestOptionsFromContext(ctx)
+
+	base := httpClient
+	if opts.insecure {
+		insecure, err := insecureHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+		base = insecure
+	}
+	if opts.timeout <= 0 {
+		return base, nil
+	}
+
+	client := *base
+	client.Timeout = 0
+	return &client, nil
+}
+
+// Metadata provider ABS uses when none is specified
+const defaultMetadataProvider = "google"
+
+// Largest file the download tools will return, since the whole file is
+// embedded in the tool result
+const defaultMaxDownloadBytes = 10 << 20
+
+// Connection defaults read from the ABS_CONFIG / --config file
+type configFile struct {
+	configProfile `yaml:",inline"`
+
+	// Profile used when neither the profile parameter nor ABS_PROFILE is set
+	Profile  string                   `yaml:"profile"`
+	Profiles map[string]configProfile `yaml:"profiles"`
+}
+
+// Settings that can be given at the top level of the config file or per profile
+type configProfile struct {
+	BaseURL    string `yaml:"base_url"`
+	Token      string `yaml:"token"`
+	Timeout    string `yaml:"timeout"`
+	MaxRetries *int   `yaml:"max_retries"`
+}
+
+// Parsed config file; empty unless one was given
+var fileConfig configFile
+
+// Helper to read a YAML or JSON config file, rejecting unknown keys so typos
+// don't go unnoticed. An empty path yields an empty config.
+func loadConfigFile(path string) (configFile, error) {
+	var cfg configFile
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	if _, err := cfg.settings(cfg.Profile); err != nil {
+		return cfg, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Helper to pick the settings for a profile, filling anything the profile
+// leaves unset from the top level. An empty name uses the file's default
+// profile, if any.
+func (c configFile) settings(profile string) (configProfile, error) {
+	if profile == "" {
+		profile = c.Profile
+	}
+	if profile == "" {
+		return c.configProfile, nil
+	}
+
+	settings, ok := c.Profiles[profile]
+	if !ok {
+		return configProfile{}, fmt.Errorf("unknown profile %q", profile)
+	}
+	if settings.BaseURL == "" {
+		settings.BaseURL = c.BaseURL
+	}
+	if settings.Token == "" {
+		settings.Token = c.Token
+	}
+	if settings.Timeout == "" {
+		settings.Timeout = c.Timeout
+	}
+	if settings.MaxRetries == nil {
+		settings.MaxRetries = c.MaxRetries
+	}
+	return settings, nil
+}
+
+// Helper to pick the config file settings for a request's profile parameter,
+// falling back to ABS_PROFILE
+func fileSettingsForRequest(request mcp.CallToolRequest) (configProfile, error) {
+	return fileConfig.settings(getEnvOrParam(request.GetString("profile", ""), "ABS_PROFILE"))
+}
+
+// Helper to resolve a setting from the request parameter, then the
+// environment, then the config file
+func getSetting(paramValue, envKey, fileValue string) string {
+	if value := getEnvOrParam(paramValue, envKey); value != "" {
+		return value
+	}
+	return fileValue
+}
+
+// applyConfigFile makes the timeout and retry settings of the config file's
+// default profile take effect where the environment doesn't set them
+func applyConfigFile(cfg configFile) error {
+	settings, err := cfg.settings(os.Getenv("ABS_PROFILE"))
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv("ABS_HTTP_TIMEOUT") == "" && settings.Timeout != "" {
+		clientConfig.timeout = parseTimeout(settings.Timeout, defaultHTTPTimeout)
+	}
+	if os.Getenv("ABS_MAX_RETRIES") == "" && settings.MaxRetries != nil && *settings.MaxRetries >= 0 {
+		maxRetries = *settings.MaxRetries
+	}
+	fileConfig = cfg
+	return nil
+}
+
+func getEnvOrParam(paramValue, envKey string) string {
+	if paramValue != "" {
+		return paramValue
+	}
+	return os.Getenv(envKey)
+}
+
+// Read-only ABS listings exposed as MCP resources
+var listingResources = []struct {
+	uri, name, description, path string
+}{
+	{"abs://libraries", "Libraries", "All libraries on the Audiobookshelf server", "/libraries"},
+	{"abs://collections", "Collections", "All collections", "/collections"},
+	{"abs://playlists", "Playlists", "All playlists of the authenticated user", "/playlists"},
+	{"abs://genres", "Genres", "All genres across the server's libraries", "/genres"},
+	{"abs://tags", "Tags", "All tags across the server's libraries", "/tags"},
+}
+
+// Helper to create a resource handler that reads an ABS listing. Resource reads
+// carry no arguments, so the connection comes from the environment only.
+func createListingResourceHandler(path string) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		baseURL, token, err := getABSConfig(mcp.CallToolRequest{})
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := absGET(ctx, baseURL, token, path)
+		if err != nil {
+			return nil, err
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(body),
+			},
+		}, nil
+	}
+}
+
+// Single ABS entities exposed as MCP resource templates, keyed by ID
+var entityResourceTemplates = []struct {
+	uriTemplate, name, description, pathTemplate, idName string
+}{
+	{"abs://library/{library_id}", "Library", "A single library by ID", "/libraries/%s", "library_id"},
+	{"abs://item/{item_id}", "Item", "A single library item (audiobook or podcast) by ID", "/items/%s", "item_id"},
+}
+
+// Helper to create a resource template handler that reads the ABS entity whose
+// ID was matched from the resource URI
+func createEntityResourceHandler(pathTemplate, idName string) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		id, err := resourceTemplateID(request, idName)
+		if err != nil {
+			return nil, err
+		}
+
+		baseURL, token, err := getABSConfig(mcp.CallToolRequest{})
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := absGET(ctx, baseURL, token, fmt.Sprintf(pathTemplate, url.PathEscape(id)))
+		if err != nil {
+			return nil, err
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(body),
+			},
+		}, nil
+	}
+}
+
+// Helper to pull a single ID out of the variables matched from a resource URI,
+// rejecting empty values and values that would add path segments
+func resourceTemplateID(request mcp.ReadResourceRequest, name string) (string, error) {
+	var id string
+	switch value := request.Params.Arguments[name].(type) {
+	case string:
+		id = value
+	case []string:
+		if len(value) == 1 {
+			id = value[0]
+		}
+	}
+
+	if id == "" || strings.ContainsAny(id, "/?#") || id == "." || id == ".." {
+		return "", fmt.Errorf("resource URI %s must contain a single %s", request.Params.URI, name)
+	}
+	return id, nil
+}
+
+// MCP transports the server can be served over
+const (
+	transportStdio = "stdio"
+	transportSSE   = "sse"
+	transportHTTP  = "http"
+)
+
+// Address network transports listen on when ABS_LISTEN_ADDR is unset
+const defaultListenAddr = ":8080"
+
+// Settings for the transport the MCP server is served over
+type transportConfig struct {
+	name       string
+	listenAddr string
+	// Path prefix for the network transports' endpoints, e.g. /abs when behind a proxy
+	pathPrefix string
+}
+
+// How long network transports wait for in-flight requests when shutting down
+const shutdownTimeout = 10 * time.Second
+
+// A transport the MCP server is served over; Start blocks until the transport
+// stops or ctx is cancelled, in which case it shuts down cleanly
+type mcpTransport interface {
+	Start(ctx context.Context) error
+}
+
+// Serves the MCP server over stdin/stdout until ctx is cancelled or stdin
+// reaches EOF
+type stdioTransport struct {
+	mcpServer *server.MCPServer
+	in        io.Reader
+	out       io.Writer
+}
+
+func (t *stdioTransport) Start(ctx context.Context) error {
+	err := server.NewStdioServer(t.mcpServer).Listen(ctx, t.in, t.out)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// Serves the MCP server over SSE on listenAddr
+type sseTransport struct {
+	sseServer  *server.SSEServer
+	listenAddr string
+	basePath   string
+}
+
+func (t *sseTransport) Start(ctx context.Context) error {
+	logger.Info("serving MCP over SSE", "url", t.listenAddr+t.basePath+"/sse")
+	return serveUntilDone(ctx, func() error { return t.sseServer.Start(t.listenAddr) }, t.sseServer.Shutdown)
+}
+
+// Serves the MCP server over streamable HTTP on listenAddr
+type httpTransport struct {
+	httpServer   *server.StreamableHTTPServer
+	listenAddr   string
+	endpointPath string
+}
+
+func (t *httpTransport) Start(ctx context.Context) error {
+	logger.Info("serving MCP over streamable HTTP", "url", t.listenAddr+t.endpointPath)
+	return serveUntilDone(ctx, func() error { return t.httpServer.Start(t.listenAddr) }, t.httpServer.Shutdown)
+}
+
+// Helper to run a network server until it fails or ctx is cancelled, then
+// shut it down, giving in-flight requests up to shutdownTimeout to finish
+func serveUntilDone(ctx context.Context, start func() error, shutdown func(context.Context) error) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- start() }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// signalContext returns a context that is cancelled on SIGINT or SIGTERM,
+// so a running transport can shut down cleanly
+func signalContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+}
+
+// Helper to build the configured transport, defaulting to stdio
+func newTransport(s *server.MCPServer, cfg transportConfig) (mcpTransport, error) {
+	listenAddr := cfg.listenAddr
+	if listenAddr == "" {
+		listenAddr = defaultListenAddr
+	}
+	pathPrefix := strings.TrimRight(cfg.pathPrefix, "/")
+	if pathPrefix != "" && !strings.HasPrefix(pathPrefix, "/") {
+		pathPrefix = "/" + pathPrefix
+	}
+
+	switch strings.ToLower(strings.TrimSpace(cfg.name)) {
+	case "", transportStdio:
+		return &stdioTransport{mcpServer: s, in: os.Stdin, out: os.Stdout}, nil
+	case transportSSE:
+		return &sseTransport{
+			sseServer:  server.NewSSEServer(s, server.WithStaticBasePath(pathPrefix)),
+			listenAddr: listenAddr,
+			basePath:   pathPrefix,
+		}, nil
+	case transportHTTP:
+		endpointPath := pathPrefix + "/mcp"
+		return &httpTransport{
+			httpServer:   server.NewStreamableHTTPServer(s, server.WithEndpointPath(endpointPath)),
+			listenAddr:   listenAddr,
+			endpointPath: endpointPath,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported transport %q; expected %s, %s, or %s", cfg.name, transportStdio, transportSSE, transportHTTP)
+	}
+}
+
+// Helper to read the first of several environment variables that is set
+func getEnvAny(envKeys ...string) string {
+	for _, key := range envKeys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func getABSConfig(request mcp.CallToolRequest) (baseURL, token string, err error) {
+	serverURL, token, err := getABSServerConfig(request)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Always append /api to the server URL
+	baseURL = fmt.Sprintf("%s/api", serverURL)
+
+	return baseURL, token, nil
+}
+
+// getABSServerConfig is getABSConfig for root-level endpoints, returning the
+// server URL without the /api suffix
+func getABSServerConfig(request mcp.CallToolRequest) (serverURL, token string, err error) {
+	serverURL, err = getABSServerURL(request)
+	if err != nil {
+		return "", "", err
+	}
+
+	settings, err := fileSettingsForRequest(request)
+	if err != nil {
+		return "", "", err
+	}
+
+	tokenParam := request.GetString("token", "")
+	token = getSetting(tokenParam, "ABS_API_KEY", settings.Token)
+
+	if token == "" {
+		return "", "", fmt.Errorf("token is required: pass the token parameter, set the ABS_API_KEY environment variable, or set token in the config file (--config or ABS_CONFIG), at the top level or in the selected profile")
+	}
+
+	return serverURL, token, nil
+}
+
+// getABSServerURL resolves just the normalized server URL, for calls such as
+// login that are made before a token exists
+func getABSServerURL(request mcp.CallToolRequest) (string, error) {
+	settings, err := fileSettingsForRequest(request)
+	if err != nil {
+		return "", err
+	}
+
+	baseURLParam := request.GetString("base_url", "")
+	serverURL := getSetting(baseURLParam, "ABS_BASE_URL", settings.BaseURL)
+
+	if serverURL == "" {
+		return "", fmt.Errorf("base_url is required: pass the base_url parameter, set the ABS_BASE_URL environment variable, or set base_url in the config file (--config or ABS_CONFIG), at the top level or in the selected profile")
+	}
+
+	return normalizeBaseURL(serverURL)
+}
+
+// Helper to reduce a configured base URL to the server URL, keeping any
+// reverse-proxy path prefix such as /audiobookshelf but dropping a trailing
+// /api, trailing slashes, and any query string or fragment. URLs without a
+// scheme are assumed to be https.
+func normalizeBaseURL(baseURL string) (string, error) {
+	baseURL = strings.TrimSpace(baseURL)
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("base_url must be an http or https URL such as https://abs.example.com, got %q", baseURL)
+	}
+
+	parsed.Path = trimAPIPath(parsed.Path)
+	parsed.RawPath = ""
+	parsed.RawQuery = ""
+	parsed.ForceQuery = false
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+
+	return parsed.String(), nil
+}
+
+// Helper to strip trailing slashes and a trailing /api segment from a path
+func trimAPIPath(path string) string {
+	path = strings.TrimRight(path, "/")
+	path = strings.TrimSuffix(path, "/api")
+	return strings.TrimRight(path, "/")
+}
+
+// Helper to add base_url and token parameters to a tool
+func withABSAuth() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithString("base_url",
+			mcp.Description("Audiobookshelf server URL, e.g. https://abs.example.com; a trailing /api is accepted (defaults to ABS_BASE_URL env var)"),
+		),
+		mcp.WithString("token",
+			mcp.Description("Bearer token used to authenticate with Audiobookshelf (defaults to ABS_API_KEY env var)"),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Timeout for this call's ABS API requests in seconds (defaults to ABS_HTTP_TIMEOUT env var, or 10s)"),
+		),
+		mcp.WithNumber("max_retries",
+			mcp.Description("Times to retry a failed ABS API request, 0 to fail fast (defaults to ABS_MAX_RETRIES env var, or 2)"),
+		),
+		mcp.WithBoolean("insecure",
+			mcp.Description("Skip TLS certificate verification for this call, e.g. for self-signed certificates"),
+		),
+		mcp.WithBoolean("auth_in_query",
+			mcp.Description("Also send the token as a ?token= query parameter, for endpoints that don't read the Authorization header"),
+		),
+		mcp.WithBoolean("no_cache",
+			mcp.Description("Fetch fresh data instead of using a cached response (only relevant when ABS_CACHE_TTL is set)"),
+		),
+		mcp.WithBoolean("structured",
+			mcp.Description("Also return a JSON object response as structured content (defaults to ABS_STRUCTURED_OUTPUT env var)"),
+		),
+		mcp.WithBoolean("pretty",
+			mcp.Description("Indent JSON responses for easier reading"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Truncate responses longer than this many bytes, noting how much was cut (defaults to ABS_MAX_RESPONSE_BYTES env var, or no limit)"),
+		),
+		mcp.WithString("profile",
+			mcp.Description("Config file profile to take base_url and token from (defaults to ABS_PROFILE env var, or the file's default profile)"),
+		),
+	}
+}
+
+// readOnlyTool annotates a tool that only reads from Audiobookshelf.
+func readOnlyTool() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithReadOnlyHintAnnotation(true)(t)
+		mcp.WithDestructiveHintAnnotation(false)(t)
+		mcp.WithIdempotentHintAnnotation(true)(t)
+	}
+}
+
+// additiveTool annotates a tool that creates or triggers something without
+// overwriting or removing existing data.
+func additiveTool() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithReadOnlyHintAnnotation(false)(t)
+		mcp.WithDestructiveHintAnnotation(false)(t)
+	}
+}
+
+// destructiveTool annotates a tool that overwrites or removes existing data.
+// Repeating the same call has no further effect.
+func destructiveTool() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithReadOnlyHintAnnotation(false)(t)
+		mcp.WithDestructiveHintAnnotation(true)(t)
+		mcp.WithIdempotentHintAnnotation(true)(t)
+	}
+}
+
+// Helper to create a simple list/get tool pair
+func createSimpleGETHandler(path string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		baseURL, token, err := getABSConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		body, err := absGET(ctx, baseURL, token, path)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return mcp.NewToolResultText(string(body)), nil
+	}
+}
+
+// Helper to create a POST handler with an ID parameter and no payload
+func createPOSTByIDHandler(pathTemplate, idParamName string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		baseURL, token, err := getABSConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		id, err := request.RequireString(idParamName)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		body, err := absPOST(ctx, baseURL, token, fmt.Sprintf(pathTemplate, id), nil)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return mcp.NewToolResultText(string(body)), nil
+	}
+}
+
+// Helper to create a DELETE handler with an ID parameter
+func createDELETEByIDHandler(pathTemplate, idParamName string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		baseURL, token, err := getABSConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		id, err := request.RequireString(idParamName)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		body, err := absDELETE(ctx, baseURL, token, fmt.Sprintf(pathTemplate, id))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return mcp.NewToolResultText(string(body)), nil
+	}
+}
+
+// Helper to create a DELETE handler with an ID parameter, guarded by confirm=true
+func createConfirmedDELETEByIDHandler(pathTemplate, idParamName string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		baseURL, token, err := getABSConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		id, err := request.RequireString(idParamName)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		if err := requireConfirm(request); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		body, err := absDELETE(ctx, baseURL, token, fmt.Sprintf(pathTemplate, id))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return mcp.NewToolResultText(string(body)), nil
+	}
+}
+
+// Builds the query string for an endpoint from the request parameters
+type queryBuilder func(request mcp.CallToolRequest) (url.Values, error)
+
+// Helper to create a simple GET handler whose query string is built from the request
+func createSimpleGETQueryHandler(path string, buildQuery queryBuilder) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		baseURL, token, err := getABSConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		query, err := buildQuery(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		fullPath := path
+		if len(query) > 0 {
+			fullPath = fmt.Sprintf("%s?%s", path, query.Encode())
+		}
+
+		body, err := absGET(ctx, baseURL, token, fullPath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return mcp.NewToolResultText(string(body)), nil
+	}
+}
+
+// Helper to create a GET handler for root-level endpoints (without /api prefix)
+func createRootGETHandler(path string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Don't append /api for root-level endpoints
+		serverURL, token, err := getABSServerConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		body, err := absGET(ctx, serverURL, token, path)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return mcp.NewToolResultText(string(body)), nil
+	}
+}
+
+// Helper to create a GET handler with an ID parameter
+func createGETByIDHandler(pathTemplate, idParamName string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		baseURL, token, err := getABSConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		id, err := request.RequireString(idParamName)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		body, contentType, err := absGETWithContentType(ctx, baseURL, token, fmt.Sprintf(pathTemplate, id))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return contentAwareResult(body, contentType), nil
+	}
+}
+
+// Helper to create a GET handler with an ID parameter whose query string is built from the request
+func createGETByIDQueryHandler(pathTemplate, idParamName string, buildQuery queryBuilder) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		baseURL, token, err := getABSConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		id, err := request.RequireString(idParamName)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		query, err := buildQuery(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		path := fmt.Sprintf(pathTemplate, id)
+		if len(query) > 0 {
+			path = fmt.Sprintf("%s?%s", path, query.Encode())
+		}
+
+		body, err := absGET(ctx, baseURL, token, path)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return mcp.NewToolResultText(string(body)), nil
+	}
+}
+
+// Helper to create a GET handler with ID and optional sub-resource
+func createGETByIDWithSubResourceHandler(basePath, idParamName string, subResources []string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return createGETByIDWithSubResourceQueryHandler(basePath, idParamName, subResources, nil)
+}
+
+// Helper to create a GET handler with ID and optional sub-resource, where
+// sub-resources may carry their own query parameters
+func createGETByIDWithSubResourceQueryHandler(basePath, idParamName string, subResources []string, queries map[string]queryBuilder) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		baseURL, token, err := getABSConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		id, err := request.RequireString(idParamName)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Build the path - check if any sub-resource is requested
+		path := fmt.Sprintf(basePath, id)
+		for _, subResource := range subResources {
+			if request.GetBool(subResource, false) {
+				path = fmt.Sprintf("%s/%s", path, subResource)
+
+				if buildQuery, ok := queries[subResource]; ok {
+					query, err := buildQuery(request)
+					if err != nil {
+						return mcp.NewToolResultError(err.Error()), nil
+					}
+					if len(query) > 0 {
+						path = fmt.Sprintf("%s?%s", path, query.Encode())
+					}
+				}
+				break // Only one sub-resource at a time
+			}
+		}
+
+		body, contentType, err := absGETWithContentType(ctx, baseURL, token, path)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return contentAwareResult(body, contentType), nil
+	}
+}
+
+// Query parameters for paginated endpoints, defaulting to the given page size
+func paginationQuery(defaultItemsPerPage int) queryBuilder {
+	return func(request mcp.CallToolRequest) (url.Values, error) {
+		query := url.Values{}
+		query.Set("itemsPerPage", strconv.Itoa(request.GetInt("items_per_page", defaultItemsPerPage)))
+		query.Set("page", strconv.Itoa(request.GetInt("page", 0)))
+		return query, nil
+	}
+}
+
+// Query parameters for endpoints with an optional limit and page
+func limitPageQuery(request mcp.CallToolRequest) (url.Values, error) {
+	query := url.Values{}
+	if limit := request.GetInt("limit", 0); limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if page := request.GetInt("page", 0); page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	return query, nil
+}
+
+// Query parameters for the library items sub-resource
+func libraryItemsQuery(request mcp.CallToolRequest) (url.Values, error) {
+	query := url.Values{}
+	if sort := request.GetString("sort", ""); sort != "" {
+		query.Set("sort", sort)
+	}
+	if request.GetBool("desc", false) {
+		query.Set("desc", "1")
+	}
+	if filter := request.GetString("filter", ""); filter != "" {
+		query.Set("filter", filter)
+	}
+	if request.GetBool("minified", false) {
+		query.Set("minified", "1")
+	}
+	if request.GetBool("collapseseries", false) {
+		query.Set("collapseseries", "1")
+	}
+	return query, nil
+}
+
+// Query parameters for the library recent-episodes sub-resource; a limit of 0 returns everything
+func libraryRecentEpisodesQuery(request mcp.CallToolRequest) (url.Values, error) {
+	query := url.Values{}
+	if limit := request.GetInt("limit", 25); limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if page := request.GetInt("page", 0); page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	return query, nil
+}
+
+// Query parameters for the library personalized sub-resource; without a limit ABS uses its own default
+func libraryPersonalizedQuery(request mcp.CallToolRequest) (url.Values, error) {
+	query := url.Values{}
+	if limit := request.GetInt("limit", 0); limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query, nil
+}
+
+// Query parameters for the library search sub-resource; ABS has no "all" for
+// search, so a limit of 0 or less uses the default
+func librarySearchQuery(request mcp.CallToolRequest) (url.Values, error) {
+	searchQuery := strings.TrimSpace(request.GetString("query", ""))
+	if searchQuery == "" {
+		return nil, fmt.Errorf("query parameter is required when search=true")
+	}
+
+	limit := request.GetInt("limit", defaultLibrarySearchLimit)
+	if limit <= 0 {
+		limit = defaultLibrarySearchLimit
+	}
+
+	query := url.Values{}
+	query.Set("q", searchQuery)
+	query.Set("limit", strconv.Itoa(limit))
+	return query, nil
+}
+
+// Results per category the library search returns when no limit is given
+const defaultLibrarySearchLimit = 12
+
+func absGET(ctx context.Context, baseURL, token, path string) ([]byte, error) {
+	return absRequest(ctx, http.MethodGet, baseURL, token, path, nil)
+}
+
+func absPOST(ctx context.Context, baseURL, token, path string, payload interface{}) ([]byte, error) {
+	return absRequest(ctx, http.MethodPost, baseURL, token, path, payload)
+}
+
+func absPATCH(ctx context.Context, baseURL, token, path string, payload interface{}) ([]byte, error) {
+	return absRequest(ctx, http.MethodPatch, baseURL, token, path, payload)
+}
+
+func absDELETE(ctx context.Context, baseURL, token, path string) ([]byte, error) {
+	return absRequest(ctx, http.MethodDelete, baseURL, token, path, nil)
+}
+
+// absRequest performs a request against the ABS API, JSON-encoding the payload
+// when one is given, and returns the response body for 2xx responses
+func absRequest(ctx context.Context, method, baseURL, token, path string, payload interface{}) ([]byte, error) {
+	body, _, err := absDo(ctx, method, baseURL, token, path, payload)
+	return body, err
+}
+
+// absGETWithContentType is absGET for endpoints that may return non-JSON
+// content such as images, reporting the response Content-Type alongside the body
+func absGETWithContentType(ctx context.Context, baseURL, token, path string) ([]byte, string, error) {
+	body, header, err := absDo(ctx, http.MethodGet, baseURL, token, path, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, header.Get("Content-Type"), nil
+}
+
+// Requests per second allowed to each ABS host, from ABS_RATE_LIMIT; 0 means
+// no limit
+var rateLimit = parseRateLimit(os.Getenv("ABS_RATE_LIMIT"))
+
+// Helper to parse a requests-per-second limit, treating empty, invalid, or
+// non-positive values as no limit
+func parseRateLimit(value string) float64 {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || rate <= 0 {
+		return 0
+	}
+	return rate
+}
+
+// A token bucket that refills at rate tokens per second and holds at most one,
+// so requests are spaced evenly rather than sent in bursts
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: 1, last: time.Now()}
+}
+
+// wait takes a token, blocking until one is available or ctx is done. Each
+// caller reserves its token up front, so concurrent callers queue in order.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(1, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	tokens := b.tokens
+	b.mu.Unlock()
+
+	if tokens >= 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, time.Duration(-tokens/b.rate*float64(time.Second))); err != nil {
+		// Hand the reserved token back for the callers behind this one
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// One token bucket per ABS host
+type hostRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+var rateLimiter = &hostRateLimiter{buckets: map[string]*tokenBucket{}}
+
+// wait blocks until a request to host is allowed under ABS_RATE_LIMIT
+func (l *hostRateLimiter) wait(ctx context.Context, host string) error {
+	if rateLimit <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = newTokenBucket(rateLimit)
+		l.buckets[host] = bucket
+	}
+	l.mu.Unlock()
+
+	return bucket.wait(ctx)
+}
+
+// A counting semaphore bounding how much work runs at once; a nil semaphore
+// never blocks
+type semaphore chan struct{}
+
+func newSemaphore(n int) semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(semaphore, n)
+}
+
+// acquire blocks until a slot is free or ctx is done
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// Bounds the ABS requests in flight at once across all tool calls, from
+// ABS_MAX_CONCURRENCY; unlimited when unset. A slot is held from sending a
+// request until its response body is closed, so code that fans out to many
+// items only needs to issue its requests concurrently to stay within it.
+var requestSemaphore = newSemaphore(parsePositiveInt(os.Getenv("ABS_MAX_CONCURRENCY"), 0))
+
+// releasingBody releases a semaphore slot when the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// How long successful GET responses are reused, from ABS_CACHE_TTL; 0 turns
+// caching off
+var cacheTTL = parseTimeout(os.Getenv("ABS_CACHE_TTL"), 0)
+
+// Identifies a cached response. The token is part of the key so users never
+// see each other's cached data.
+type cacheKey struct {
+	baseURL, token, path string
+}
+
+// Limits on what the cache holds, so a long-running server stays bounded:
+// single responses over cacheMaxEntryBytes are never cached, the least
+// recently used ones are evicted past ABS_CACHE_MAX_BYTES in total, and
+// expired responses with an ETag are kept for revalidation only for
+// cacheRevalidateWindow. A sweep drops expired entries every cacheSweepInterval.
+const (
+	cacheMaxEntryBytes    = 1 << 20
+	defaultCacheMaxBytes  = 32 << 20
+	cacheRevalidateWindow = 10 * time.Minute
+	cacheSweepInterval    = time.Minute
+)
+
+var cacheMaxBytes = parsePositiveInt(os.Getenv("ABS_CACHE_MAX_BYTES"), defaultCacheMaxBytes)
+
+type cacheEntry struct {
+	key     cacheKey
+	body    []byte
+	header  http.Header
+	etag    string
+	expires time.Time
+}
+
+func (e cacheEntry) fresh() bool {
+	return time.Now().Before(e.expires)
+}
+
+// Whether an entry can be dropped: it has expired and, if it has an ETag, is
+// past the revalidation window too
+func (e cacheEntry) stale(now time.Time) bool {
+	if e.etag != "" {
+		return now.After(e.expires.Add(cacheRevalidateWindow))
+	}
+	return !now.Before(e.expires)
+}
+
+// In-memory LRU cache of successful GET responses, bounded by maxBytes of
+// response bodies
+type responseCache struct {
+	mu        sync.Mutex
+	entries   map[cacheKey]*list.Element
+	order     *list.List // most recently used first
+	size      int
+	maxBytes  int
+	sweepOnce sync.Once
+}
+
+func newResponseCache(maxBytes int) *responseCache {
+	return &responseCache{entries: map[cacheKey]*list.Element{}, order: list.New(), maxBytes: maxBytes}
+}
+
+var getCache = newResponseCache(cacheMaxBytes)
+
+// Helper to decide whether a response body is worth caching: JSON API
+// responses are, while large bodies and binary data such as covers aren't
+func cacheableBody(body []byte) bool {
+	return len(body) <= cacheMaxEntryBytes && json.Valid(body)
+}
+
+// Helper to look up a cached response. Expired responses are dropped unless
+// they carry an ETag, in which case they are returned for revalidation.
+func (c *responseCache) get(key cacheKey) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	entry := element.Value.(cacheEntry)
+	if entry.stale(time.Now()) {
+		c.remove(element)
+		return cacheEntry{}, false
+	}
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+func (c *responseCache) set(key cacheKey, body []byte, header http.Header) {
+	c.sweepOnce.Do(func() { go c.sweepEvery(cacheSweepInterval) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	if len(body) > c.maxBytes {
+		return
+	}
+	c.entries[key] = c.order.PushFront(cacheEntry{
+		key:     key,
+		body:    body,
+		header:  header,
+		etag:    header.Get("ETag"),
+		expires: time.Now().Add(cacheTTL),
+	})
+	c.size += len(body)
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+// Helper to drop one entry; the caller must hold c.mu
+func (c *responseCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= len(entry.body)
+}
+
+// sweep drops every entry that can no longer be served or revalidated,
+// returning how many there were
+func (c *responseCache) sweep(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(cacheEntry).stale(now) {
+			c.remove(element)
+			n++
+		}
+		element = next
+	}
+	return n
+}
+
+// Helper to run sweep periodically for the life of the process
+func (c *responseCache) sweepEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		c.sweep(now)
+	}
+}
+
+// Helper to drop every cached response, returning how many there were
+func (c *responseCache) clear() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.entries)
+	c.entries = map[cacheKey]*list.Element{}
+	c.order.Init()
+	c.size = 0
+	return n
+}
+
+// Other listings that embed a resource, and so go stale when it changes
+var relatedCachePrefixes = map[string][]string{
+	"/authors":  {"/libraries"},
+	"/items":    {"/libraries", "/me"},
+	"/podcasts": {"/libraries"},
+	"/session":  {"/me", "/sessions"},
+	"/upload":   {"/libraries"},
+}
+
+// invalidate drops the cached responses for baseURL, for every token, that a
+// write to path may have made stale: everything under the path's first
+// segment, such as /collections for a change to /collections/col_1/book,
+// plus any related listings
+func (c *responseCache) invalidate(baseURL, path string) {
+	prefix := firstPathSegment(path)
+	prefixes := append([]string{prefix}, relatedCachePrefixes[prefix]...)
+
+	c.invalidateWhere(baseURL, func(cachedPath string) bool {
+		for _, p := range prefixes {
+			if cachedPath == p || strings.HasPrefix(cachedPath, p+"/") || strings.HasPrefix(cachedPath, p+"?") {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// invalidateWhere drops the cached responses for baseURL, for every token,
+// whose path matches
+func (c *responseCache) invalidateWhere(baseURL string, match func(path string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if key.baseURL == baseURL && match(key.path) {
+			c.remove(element)
+		}
+	}
+}
+
+// Helper to mark a request as one that must reach ABS rather than be served
+// from the cache
+func noCacheContext(ctx context.Context) context.Context {
+	opts := requestOptionsFromContext(ctx)
+	opts.noCache = true
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
+
+// Helper to reduce a request path to its first segment, e.g. /collections
+// for /collections/col_1/book?x=1
+func firstPathSegment(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return "/" + segment
+}
+
+// absDo is the shared request path used by all ABS API calls; it returns the
+// response body and headers for 2xx responses. When ABS_CACHE_TTL is set, GET
+// responses are served from the cache until they expire, unless the call
+// passed no_cache. Cached responses with an ETag are then revalidated with
+// If-None-Match, and reused if the server answers 304 Not Modified.
+func absDo(ctx context.Context, method, baseURL, token, path string, payload interface{}) ([]byte, http.Header, error) {
+	cacheable := method == http.MethodGet && cacheTTL > 0
+	key := cacheKey{baseURL: baseURL, token: token, path: path}
+
+	var cached cacheEntry
+	var header http.Header
+	if cacheable {
+		entry, ok := getCache.get(key)
+		if ok && entry.fresh() && !requestOptionsFromContext(ctx).noCache {
+			return entry.body, entry.header, nil
+		}
+		if ok && entry.etag != "" {
+			cached = entry
+			header = http.Header{"If-None-Match": {entry.etag}}
+		}
+	}
+
+	resp, err := absSend(ctx, method, baseURL, token, path, payload, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		getCache.set(key, cached.body, cached.header)
+		return cached.body, cached.header, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if cacheable && cacheableBody(body) {
+		getCache.set(key, body, resp.Header)
+	}
+	return body, resp.Header, nil
+}
+
+// absGETLimited is absGETWithContentType for file downloads, failing instead
+// of buffering the whole body when the response exceeds maxBytes
+func absGETLimited(ctx context.Context, baseURL, token, path string, maxBytes int64) ([]byte, string, error) {
+	resp, err := absSend(ctx, http.MethodGet, baseURL, token, path, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("file is %d bytes, larger than the %d byte limit", resp.ContentLength, maxBytes)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("read response: %w", err)
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, "", fmt.Errorf("file is larger than the %d byte limit", maxBytes)
+	}
+
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// absSend builds and sends a JSON ABS API request, returning the open
+// response for 2xx statuses; callers must close the response body
+func absSend(ctx context.Context, method, baseURL, token, path string, payload interface{}, header http.Header) (*http.Response, error) {
+	var body []byte
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("marshal payload: %w", err)
+		}
+		body = jsonData
+	}
+
+	header = header.Clone()
+	if method != http.MethodGet {
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("Content-Type", "application/json")
+	}
+
+	return absSendBody(ctx, method, baseURL, token, path, body, header)
+}
+
+// absSendBody sends an already-encoded request body with the given headers,
+// such as Content-Type, retrying 429 and (when the method allows it) 5xx
+// responses, waiting for the server's Retry-After when given and exponential
+// backoff otherwise. A 304 answer to an If-None-Match request is returned as
+// a success.
+func absSendBody(ctx context.Context, method, baseURL, token, path string, body []byte, header http.Header) (*http.Response, error) {
+	fullURL := strings.TrimSuffix(baseURL, "/") + path
+	if requestOptionsFromContext(ctx).authInQuery && token != "" {
+		withToken, err := addTokenQuery(fullURL, token)
+		if err != nil {
+			return nil, fmt.Errorf("build request: %w", err)
+		}
+		fullURL = withToken
+	}
+	retries := maxRetriesForRequest(ctx)
+
+	client, err := clientForRequest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("configure HTTP client: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, fullURL, bodyReader)
+		if err != nil {
+			return nil, fmt.Errorf("build request: %w", err)
+		}
+
+		for key, value := range extraHeaders {
+			req.Header.Set(key, value)
+		}
+		// Setting Accept-Encoding ourselves turns off the transport's transparent
+		// decompression, so gzip responses are decoded by decodeGzipBody
+		req.Header.Set("Accept-Encoding", "gzip")
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		setAuthHeaders(req, token)
+
+		if err := rateLimiter.wait(ctx, req.URL.Host); err != nil {
+			return nil, fmt.Errorf("wait for rate limit: %w", err)
+		}
+		if err := requestSemaphore.acquire(ctx); err != nil {
+			return nil, fmt.Errorf("wait for a free connection: %w", err)
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		logRequest(ctx, req, body, resp, err, time.Since(start))
+		if err != nil {
+			requestSemaphore.release()
+			// Transport errors quote the URL, which holds the token with auth_in_query
+			return nil, fmt.Errorf("call ABS API: %w", redactedError{err})
+		}
+		resp.Body = &releasingBody{ReadCloser: resp.Body, release: requestSemaphore.release}
+		if err := decodeGzipBody(resp); err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if method != http.MethodGet {
+				getCache.invalidate(baseURL, path)
+			}
+			return resp, nil
+		}
+		if resp.StatusCode == http.StatusNotModified && header.Get("If-None-Match") != "" {
+			return resp, nil
+		}
+
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		apiErr := fmt.Errorf("ABS API returned %s: %s", resp.Status, redactSecrets(string(respBody)))
+
+		if attempt >= retries || !shouldRetry(method, resp.StatusCode) {
+			return nil, apiErr
+		}
+
+		delay := retryBackoff(attempt)
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			delay = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, apiErr
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, apiErr
+		}
+	}
+}
+
+// Helper to write one debug line for an ABS request when ABS_DEBUG is set,
+// with its method, URL, and body, and the response status or error and how
+// long it took. Credentials are masked here rather than left to the logger.
+func logRequest(ctx context.Context, req *http.Request, body []byte, resp *http.Response, err error, elapsed time.Duration) {
+	if !debugRequests {
+		return
+	}
+	attrs := []any{"method", req.Method, "url", debugURL(req.URL), "duration", elapsed}
+	if len(body) > 0 {
+		attrs = append(attrs, "body", debugBody(body))
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	} else {
+		attrs = append(attrs, "status", resp.StatusCode)
+	}
+	// ABS_DEBUG asked for these lines, so they go straight to the handler,
+	// bypassing the ABS_LOG_LEVEL filter
+	record := slog.NewRecord(time.Now(), slog.LevelDebug, "ABS request", 0)
+	record.Add(attrs...)
+	logger.Handler().Handle(ctx, record)
+}
+
+// Helper to format a request URL for logging, masking the token query
+// parameter that auth_in_query adds
+func debugURL(u *url.URL) string {
+	query := u.Query()
+	if !query.Has("token") {
+		return u.String()
+	}
+	masked := *u
+	query.Set("token", "[REDACTED]")
+	masked.RawQuery = query.Encode()
+	return masked.String()
+}
+
+// Helper to decide whether a JSON field holds a credential, such as a login
+// or SMTP password or an API token, going by its name
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+	switch name {
+	case "pass", "passwd", "smtppass", "authorization":
+		return true
+	}
+	return strings.HasSuffix(name, "password") || strings.HasSuffix(name, "secret") ||
+		strings.HasSuffix(name, "token") || strings.HasSuffix(name, "apikey")
+}
+
+// Helper to mask the values of credential fields anywhere in a decoded JSON
+// value
+func redactFields(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isSensitiveField(key) {
+				v[key] = "[REDACTED]"
+			} else {
+				v[key] = redactFields(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactFields(item)
+		}
+	}
+	return value
+}
+
+// Helper to format a request body for logging. JSON bodies have credential
+// fields masked and are cut down to debugBodyLimit bytes, without splitting a
+// UTF-8 character; other bodies, such as file uploads, are only described.
+func debugBody(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Sprintf("(%d bytes, not JSON)", len(body))
+	}
+	redacted, err := json.Marshal(redactFields(value))
+	if err != nil {
+		return fmt.Sprintf("(%d bytes)", len(body))
+	}
+	if len(redacted) <= debugBodyLimit {
+		return string(redacted)
+	}
+	cut := debugBodyLimit
+	for cut > 0 && !utf8.RuneStart(redacted[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... (%d bytes)", redacted[:cut], len(body))
+}
+
+// gzipBody closes both the gzip reader and the underlying response body
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+// decodeGzipBody replaces a gzip-encoded response body with its decoded
+// content, so callers always read plain bytes
+func decodeGzipBody(resp *http.Response) error {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			// An empty body, as with 204 or 304 responses
+			resp.Header.Del("Content-Encoding")
+			return nil
+		}
+		return fmt.Errorf("decode gzip: %w", err)
+	}
+
+	resp.Body = &gzipBody{Reader: reader, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// Helper to set the request's credentials: the ABS bearer token in Authorization,
+// and any reverse-proxy Basic credentials in Authorization when there is no token
+// or in Proxy-Authorization alongside it
+func setAuthHeaders(req *http.Request, token string) {
+	if basicAuthUser != "" || basicAuthPass != "" {
+		req.SetBasicAuth(basicAuthUser, basicAuthPass)
+		if token != "" {
+			req.Header.Set("Proxy-Authorization", req.Header.Get("Authorization"))
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// Helper to add the token to a URL's query string, keeping any existing parameters
+func addTokenQuery(rawURL, token string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsed.Query()
+	query.Set("token", token)
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
+
+// Helper to decide whether a failed response is worth retrying; rate-limited
+// requests were never processed so are always retried, while 5xx responses to
+// non-idempotent methods are only retried when ABS_RETRY_NON_IDEMPOTENT is enabled
+func shouldRetry(method string, statusCode int) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if statusCode < 500 {
+		return false
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return retryNonIdempotent
+}
+
+// Helper to parse a Retry-After header given as delay seconds or an HTTP-date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// Helper to compute the exponential backoff before retry number attempt+1
+func retryBackoff(attempt int) time.Duration {
+	return retryBaseDelay << attempt
+}
+
+// Helper to wait for d, returning early with the context's error if it is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// absPOSTMultipart sends form fields and a single local file as a
+// multipart/form-data POST, for endpoints such as /upload that don't take JSON
+func absPOSTMultipart(ctx context.Context, baseURL, token, path string, fields map[string]string, fileField, filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fieldNames := make([]string, 0, len(fields))
+	for name := range fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+	for _, name := range fieldNames {
+		if err := writer.WriteField(name, fields[name]); err != nil {
+			return nil, fmt.Errorf("write form field %s: %w", name, err)
+		}
+	}
+
+	part, err := writer.CreateFormFile(fileField, filepath.Base(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("create form file: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("finish multipart body: %w", err)
+	}
+
+	resp, err := absSendBody(ctx, http.MethodPost, baseURL, token, path, buf.Bytes(), http.Header{"Content-Type": {writer.FormDataContentType()}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	return body, nil
+}
+
+// Helper to turn a response into a tool result, returning images as MCP image
+// content rather than dumping the raw bytes into text
+func contentAwareResult(body []byte, contentType string) *mcp.CallToolResult {
+	mimeType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+
+	if strings.HasPrefix(mimeType, "image/") {
+		data := base64.StdEncoding.EncodeToString(body)
+		return mcp.NewToolResultImage(fmt.Sprintf("%s image (%d bytes)", mimeType, len(body)), data, mimeType)
+	}
+
+	return mcp.NewToolResultText(string(body))
+}
+
+// Helper to turn a downloaded file into a tool result: text-like files are
+// returned as text, images and audio as their MCP content types, and anything
+// else as a base64 blob resource identified by uri
+func fileContentResult(body []byte, contentType, uri string) *mcp.CallToolResult {
+	mimeType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+
+	summary := fmt.Sprintf("%s file (%d bytes)", mimeType, len(body))
+	data := base64.StdEncoding.EncodeToString(body)
+
+	switch {
+	case isTextMIMEType(mimeType):
+		return mcp.NewToolResultText(string(body))
+	case strings.HasPrefix(mimeType, "image/"):
+		return mcp.NewToolResultImage(summary, data, mimeType)
+	case strings.HasPrefix(mimeType, "audio/"):
+		return mcp.NewToolResultAudio(summary, data, mimeType)
+	default:
+		return mcp.NewToolResultResource(summary, mcp.BlobResourceContents{
+			URI:      uri,
+			MIMEType: mimeType,
+			Blob:     data,
+		})
+	}
+}
+
+// Helper to decide whether a MIME type is safe to return as plain text
+func isTextMIMEType(mimeType string) bool {
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+	switch mimeType {
+	case "application/json", "application/xml", "application/javascript", "application/x-subrip":
+		return true
+	}
+	return strings.HasSuffix(mimeType, "+json") || strings.HasSuffix(mimeType, "+xml")
+}
+
+// Helper to read an optional boolean, reporting whether it was supplied at all
+// so that an explicit false can be told apart from an omitted parameter
+func optionalBool(request mcp.CallToolRequest, key string) (value, ok bool) {
+	if _, present := request.GetArguments()[key]; !present {
+		return false, false
+	}
+	return request.GetBool(key, false), true
+}
+
+// Helper to guard destructive tools behind an explicit confirm=true parameter
+func requireConfirm(request mcp.CallToolRequest) error {
+	if !request.GetBool("confirm", false) {
+		return fmt.Errorf("this operation is destructive; set confirm=true to proceed")
+	}
+	return nil
+}
+
+// Helper to check that a value is an absolute http(s) URL
+func validateHTTPURL(name, value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%s must be a valid http or https URL", name)
+	}
+	return nil
+}
+
+// Helper to split a comma-separated parameter into trimmed, non-empty values
+func splitCommaList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			values = append(values, trimmed)
+		}
+	}
+	return values
+}
+
+// Helper to read a required comma-separated parameter, rejecting lists with no usable values
+func requireCommaList(request mcp.CallToolRequest, key string) ([]string, error) {
+	value, err := request.RequireString(key)
+	if err != nil {
+		return nil, err
+	}
+	values := splitCommaList(value)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s must contain at least one value", key)
+	}
+	return values, nil
+}
+
+func handleUpdateUser(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	userID, err := request.RequireString("user_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Only send the fields that were provided so unspecified settings are left untouched
+	payload := map[string]interface{}{}
+
+	if username := request.GetString("username", ""); username != "" {
+		payload["username"] = username
+	}
+	if password := request.GetString("password", ""); password != "" {
+		payload["password"] = password
+	}
+	if userType := request.GetString("type", ""); userType != "" {
+		payload["type"] = userType
+	}
+	if isActive, ok := optionalBool(request, "is_active"); ok {
+		payload["isActive"] = isActive
+	}
+	if permissionsStr := request.GetString("permissions", ""); permissionsStr != "" {
+		var permissions map[string]interface{}
+		if err := json.Unmarshal([]byte(permissionsStr), &permissions); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("permissions must be a JSON object: %v", err)), nil
+		}
+		payload["permissions"] = permissions
+	}
+
+	if len(payload) == 0 {
+		return mcp.NewToolResultError("at least one field to update is required"), nil
+	}
+
+	body, err := absPATCH(ctx, baseURL, token, fmt.Sprintf("/users/%s", userID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+// A background task as listed by GET /tasks
+type absTask struct {
+	ID          string                 `json:"id"`
+	Action      string                 `json:"action"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Error       string                 `json:"error"`
+	Data        map[string]interface{} `json:"data"`
+	IsFailed    bool                   `json:"isFailed"`
+	IsFinished  bool                   `json:"isFinished"`
+}
+
+// Helper to find the task for an action whose data field matches id
+func findTask(tasks []absTask, action, dataKey, id string) *absTask {
+	for i := range tasks {
+		if tasks[i].Action == action && tasks[i].Data[dataKey] == id {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// progressReporter returns a function that sends MCP progress notifications
+// for the call, or nil if the client didn't ask for progress.
+func progressReporter(ctx context.Context, request mcp.CallToolRequest) func(progress float64, message string) {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return nil
+	}
+
+	progressToken := request.Params.Meta.ProgressToken
+	return func(progress float64, message string) {
+		// Progress is best effort; a client that went away shouldn't fail the call
+		_ = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": progressToken,
+			"progress":      progress,
+			"message":       message,
+		})
+	}
+}
+
+// waitForTask polls GET /tasks every progressInterval until the task for
+// action and id finishes, reporting each poll. ABS drops tasks soon after
+// they finish, so a task that is no longer listed counts as done, as does one
+// that never appears within taskStartPolls polls. It returns the last state
+// seen, which is nil if the task was never listed, and an error if the task
+// is still running after progressTimeout or when ctx ends.
+func waitForTask(parent context.Context, baseURL, token, action, dataKey, id string, report func(progress float64, message string)) (*absTask, error) {
+	ctx, cancel := context.WithTimeout(parent, progressTimeout)
+	defer cancel()
+
+	// Tell the progress timeout apart from the caller's own deadline, such as
+	// timeout_seconds, or cancellation, which may stop the wait sooner
+	start := time.Now()
+	stopped := func() error {
+		elapsed := time.Since(start).Round(time.Millisecond)
+		switch {
+		case errors.Is(parent.Err(), context.DeadlineExceeded):
+			return fmt.Errorf("still running when the call's deadline passed after %s", elapsed)
+		case parent.Err() != nil:
+			return fmt.Errorf("stopped waiting after %s: %w", elapsed, parent.Err())
+		}
+		return fmt.Errorf("still running after %s", progressTimeout)
+	}
+
+	// Every poll needs the current task state, never a cached one
+	ctx = noCacheContext(ctx)
+
+	var last *absTask
+	for poll := 1; ; poll++ {
+		body, err := absGET(ctx, baseURL, token, "/tasks")
+		if err != nil {
+			if ctx.Err() != nil {
+				return last, stopped()
+			}
+			return last, err
+		}
+
+		var response struct {
+			Tasks []absTask `json:"tasks"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return last, fmt.Errorf("decode tasks: %w", err)
+		}
+
+		task := findTask(response.Tasks, action, dataKey, id)
+		switch {
+		case task == nil && (last != nil || poll >= taskStartPolls):
+			return last, nil
+		case task == nil:
+			report(float64(poll), "Waiting for the task to start")
+		case task.IsFinished:
+			return task, nil
+		default:
+			last = task
+			message := task.Title
+			if task.Description != "" {
+				message = fmt.Sprintf("%s: %s", task.Title, task.Description)
+			}
+			report(float64(poll), message)
+		}
+
+		if err := sleepContext(ctx, progressInterval); err != nil {
+			return last, stopped()
+		}
+	}
+}
+
+// Helper to describe how a polled task ended, as a tool result
+func taskResult(task *absTask, err error, finished string) *mcp.CallToolResult {
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("%s did not complete: %v", finished, err))
+	}
+	if task != nil && task.IsFailed {
+		return mcp.NewToolResultError(fmt.Sprintf("%s failed: %s", finished, task.Error))
+	}
+	if task != nil && task.Description != "" {
+		return mcp.NewToolResultText(fmt.Sprintf("%s finished: %s", finished, task.Description))
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("%s finished", finished))
+}
+
+func handleScanLibrary(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	libraryID, err := request.RequireString("library_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	path := fmt.Sprintf("/libraries/%s/scan", libraryID)
+	if request.GetBool("force", false) {
+		path += "?force=1"
+	}
+
+	body, err := absPOST(ctx, baseURL, token, path, nil)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Follow the scan to completion when the client asked for progress
+	if report := progressReporter(ctx, request); report != nil {
+		task, err := waitForTask(ctx, baseURL, token, "library-scan", "libraryId", libraryID, report)
+		return taskResult(task, err, fmt.Sprintf("Scan of library %s", libraryID)), nil
+	}
+
+	// Scans run asynchronously and the server usually replies with an empty body
+	if len(bytes.TrimSpace(body)) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("Scan started for library %s", libraryID)), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleMatchItem(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// An explicitly empty provider is left out so ABS uses the library's default
+	payload := map[string]interface{}{}
+	if provider := strings.TrimSpace(request.GetString("provider", defaultMetadataProvider)); provider != "" {
+		payload["provider"] = provider
+	}
+	if title := request.GetString("title", ""); title != "" {
+		payload["title"] = title
+	}
+	if author := request.GetString("author", ""); author != "" {
+		payload["author"] = author
+	}
+	if asin := request.GetString("asin", ""); asin != "" {
+		payload["asin"] = asin
+	}
+
+	body, err := absPOST(ctx, baseURL, token, fmt.Sprintf("/items/%s/match", itemID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleUpdateItemMedia(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Only send the metadata keys that were provided
+	metadata := map[string]interface{}{}
+
+	if title := request.GetString("title", ""); title != "" {
+		metadata["title"] = title
+	}
+	if subtitle := request.GetString("subtitle", ""); subtitle != "" {
+		metadata["subtitle"] = subtitle
+	}
+	if authorsStr := request.GetString("author", ""); authorsStr != "" {
+		var authors []map[string]interface{}
+		for _, name := range splitCommaList(authorsStr) {
+			authors = append(authors, map[string]interface{}{"name": name})
+		}
+		metadata["authors"] = authors
+	}
+	if narratorsStr := request.GetString("narrators", ""); narratorsStr != "" {
+		metadata["narrators"] = splitCommaList(narratorsStr)
+	}
+	if series := request.GetString("series", ""); series != "" {
+		seriesEntry := map[string]interface{}{"name": series}
+		if sequence := request.GetString("series_sequence", ""); sequence != "" {
+			seriesEntry["sequence"] = sequence
+		}
+		metadata["series"] = []map[string]interface{}{seriesEntry}
+	}
+	if publishedYear := request.GetString("published_year", ""); publishedYear != "" {
+		metadata["publishedYear"] = publishedYear
+	}
+
+	if len(metadata) == 0 {
+		return mcp.NewToolResultError("at least one metadata field to update is required"), nil
+	}
+
+	payload := map[string]interface{}{
+		"metadata": metadata,
+	}
+
+	body, err := absPATCH(ctx, baseURL, token, fmt.Sprintf("/items/%s/media", itemID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleDeleteItem(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := requireConfirm(request); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	path := fmt.Sprintf("/items/%s", itemID)
+	if request.GetBool("hard", false) {
+		path += "?hard=1"
+	}
+
+	body, err := absDELETE(ctx, baseURL, token, path)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleBatchDeleteItems(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemIDs, err := requireCommaList(request, "item_ids")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := requireConfirm(request); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	payload := map[string]interface{}{
+		"libraryItemIds": itemIDs,
+	}
+
+	body, err := absPOST(ctx, baseURL, token, "/items/batch/delete", payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleBatchGetItems(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemIDs, err := requireCommaList(request, "item_ids")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// IDs are sent in the order given so results line up with the request
+	payload := map[string]interface{}{
+		"libraryItemIds": itemIDs,
+	}
+
+	body, err := absPOST(ctx, baseURL, token, "/items/batch/get", payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleSyncSession(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	sessionID, err := request.RequireString("session_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	currentTime, err := request.RequireFloat("current_time")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	payload := map[string]interface{}{
+		"currentTime": currentTime,
+	}
+
+	if timeListened := request.GetFloat("time_listened", 0); timeListened > 0 {
+		payload["timeListened"] = timeListened
+	}
+
+	if duration := request.GetFloat("duration", 0); duration > 0 {
+		payload["duration"] = duration
+	}
+
+	body, err := absPOST(ctx, baseURL, token, fmt.Sprintf("/session/%s/sync", sessionID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+// Helper to build the progress path for an item, or for an episode when one is given
+func progressPath(itemID, episodeID string) string {
+	if episodeID != "" {
+		return fmt.Sprintf("/me/progress/%s/%s", itemID, episodeID)
+	}
+	return fmt.Sprintf("/me/progress/%s", itemID)
+}
+
+func handleMe(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	path := "/me"
+
+	// Check for simple boolean sub-resources first
+	if request.GetBool("listening-sessions", false) {
+		query, _ := paginationQuery(10)(request)
+		path = fmt.Sprintf("/me/listening-sessions?%s", query.Encode())
+	} else if request.GetBool("listening-stats", false) {
+		path = "/me/listening-stats"
+	} else if request.GetBool("items-in-progress", false) {
+		path = "/me/items-in-progress"
+	} else if progressItemID := request.GetString("progress_item_id", ""); progressItemID != "" {
+		// Handle progress endpoints with IDs
+		path = progressPath(progressItemID, request.GetString("progress_episode_id", ""))
+	}
+
+	body, err := absGET(ctx, baseURL, token, path)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleRemoveProgress(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	path := progressPath(itemID, request.GetString("episode_id", ""))
+
+	body, err := absDELETE(ctx, baseURL, token, path)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+// Helper to build the payload shared by the bookmark create and update endpoints
+func bookmarkPayload(bookmarkTime float64, title string) map[string]interface{} {
+	return map[string]interface{}{
+		"time":  bookmarkTime,
+		"title": title,
+	}
+}
+
+func handleCreateBookmark(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	bookmarkTime, err := request.RequireFloat("time")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// ABS stores the title as-is, so an omitted title is sent as an empty string
+	payload := bookmarkPayload(bookmarkTime, request.GetString("title", ""))
+
+	body, err := absPOST(ctx, baseURL, token, fmt.Sprintf("/me/item/%s/bookmark", itemID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleUpdateBookmark(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	bookmarkTime, err := request.RequireFloat("time")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	title, err := request.RequireString("title")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	body, err := absPATCH(ctx, baseURL, token, fmt.Sprintf("/me/item/%s/bookmark", itemID), bookmarkPayload(bookmarkTime, title))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleCreatePodcast(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	libraryID, err := request.RequireString("library_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	folderID, err := request.RequireString("folder_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	feedURL, err := request.RequireString("feed_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Build the payload with the feed nested under media.metadata
+	payload := map[string]interface{}{
+		"libraryId": libraryID,
+		"folderId":  folderID,
+		"media": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"feedUrl": feedURL,
+			},
+			"autoDownloadEpisodes": request.GetBool("auto_download", false),
+		},
+	}
+
+	if path := request.GetString("path", ""); path != "" {
+		payload["path"] = path
+	}
+
+	body, err := absPOST(ctx, baseURL, token, "/podcasts", payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleDownloadPodcastEpisodes(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	podcastID, err := request.RequireString("podcast_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var episodes []map[string]interface{}
+	if episodesStr := request.GetString("episodes", ""); episodesStr != "" {
+		if err := json.Unmarshal([]byte(episodesStr), &episodes); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("episodes must be a JSON array of episode objects: %v", err)), nil
+		}
+	} else if episodeID := request.GetString("episode_id", ""); episodeID != "" {
+		episodes = []map[string]interface{}{{"id": episodeID}}
+	}
+
+	if len(episodes) == 0 {
+		return mcp.NewToolResultError("either episodes or episode_id is required"), nil
+	}
+
+	body, err := absPOST(ctx, baseURL, token, fmt.Sprintf("/podcasts/%s/download-episodes", podcastID), episodes)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleDeletePodcastEpisode(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	podcastID, err := request.RequireString("podcast_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	episodeID, err := request.RequireString("episode_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := requireConfirm(request); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	path := fmt.Sprintf("/podcasts/%s/episode/%s", podcastID, episodeID)
+	if request.GetBool("hard", false) {
+		path += "?hard=1"
+	}
+
+	body, err := absDELETE(ctx, baseURL, token, path)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleUpdatePodcastEpisode(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	podcastID, err := request.RequireString("podcast_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	episodeID, err := request.RequireString("episode_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Only send the fields that were provided
+	payload := map[string]interface{}{}
+
+	if title := request.GetString("title", ""); title != "" {
+		payload["title"] = title
+	}
+	if description := request.GetString("description", ""); description != "" {
+		payload["description"] = description
+	}
+	if pubDate := request.GetString("pub_date", ""); pubDate != "" {
+		payload["pubDate"] = pubDate
+	}
+	if season := request.GetString("season", ""); season != "" {
+		payload["season"] = season
+	}
+
+	if len(payload) == 0 {
+		return mcp.NewToolResultError("at least one field to update is required"), nil
+	}
+
+	body, err := absPATCH(ctx, baseURL, token, fmt.Sprintf("/podcasts/%s/episode/%s", podcastID, episodeID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleSearchPodcastFeed(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	feedURL, err := request.RequireString("feed_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := validateHTTPURL("feed_url", feedURL); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	payload := map[string]interface{}{
+		"rssFeed": feedURL,
+	}
+
+	body, err := absPOST(ctx, baseURL, token, "/podcasts/feed", payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleSearchPodcasts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	term, err := request.RequireString("term")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return mcp.NewToolResultError("term must not be empty"), nil
+	}
+
+	payload := map[string]interface{}{
+		"term": term,
+	}
+
+	if country := request.GetString("country", ""); country != "" {
+		payload["country"] = country
+	}
+
+	body, err := absPOST(ctx, baseURL, token, "/search/podcast", payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleSearchAuthors(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	libraryID, err := request.RequireString("library_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	searchQuery, err := request.RequireString("q")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	query := url.Values{}
+	query.Set("q", searchQuery)
+
+	body, err := absGET(ctx, baseURL, token, fmt.Sprintf("/libraries/%s/authors?%s", libraryID, query.Encode()))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleUpdateAuthor(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	authorID, err := request.RequireString("author_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Only send the fields that were provided
+	payload := map[string]interface{}{}
+
+	if name := request.GetString("name", ""); name != "" {
+		payload["name"] = name
+	}
+	if description := request.GetString("description", ""); description != "" {
+		payload["description"] = description
+	}
+	if asin := request.GetString("asin", ""); asin != "" {
+		payload["asin"] = asin
+	}
+
+	if len(payload) == 0 {
+		return mcp.NewToolResultError("at least one field to update is required"), nil
+	}
+
+	body, err := absPATCH(ctx, baseURL, token, fmt.Sprintf("/authors/%s", authorID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+// Helper to build the payload for opening an RSS feed; the server address is
+// the ABS base URL that feed consumers will use, so the /api suffix is dropped
+func openFeedPayload(request mcp.CallToolRequest, baseURL string) (map[string]interface{}, error) {
+	payload := map[string]interface{}{
+		"serverAddress": strings.TrimSuffix(baseURL, "/api"),
+	}
+
+	if slug := request.GetString("slug", ""); slug != "" {
+		payload["slug"] = slug
+	}
+	if metadataDetailsStr := request.GetString("metadata_details", ""); metadataDetailsStr != "" {
+		var metadataDetails map[string]interface{}
+		if err := json.Unmarshal([]byte(metadataDetailsStr), &metadataDetails); err != nil {
+			return nil, fmt.Errorf("metadata_details must be a JSON object: %w", err)
+		}
+		payload["metadataDetails"] = metadataDetails
+	}
+
+	return payload, nil
+}
+
+// Helper to create a handler that publishes an entity (item, collection, or series) as an RSS feed
+func createOpenFeedHandler(entityType, idParamName string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		baseURL, token, err := getABSConfig(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		id, err := request.RequireString(idParamName)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		payload, err := openFeedPayload(request, baseURL)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		body, err := absPOST(ctx, baseURL, token, fmt.Sprintf("/feeds/%s/%s/open", entityType, id), payload)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		return mcp.NewToolResultText(string(body)), nil
+	}
+}
+
+func handleUpdateNotificationSettings(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Only send the settings that were provided
+	payload := map[string]interface{}{}
+
+	if appriseAPIURL := request.GetString("apprise_api_url", ""); appriseAPIURL != "" {
+		payload["appriseApiUrl"] = appriseAPIURL
+	}
+	if maxFailedAttempts := request.GetInt("max_failed_attempts", 0); maxFailedAttempts > 0 {
+		payload["maxFailedAttempts"] = maxFailedAttempts
+	}
+	if maxNotificationQueue := request.GetInt("max_notification_queue", 0); maxNotificationQueue > 0 {
+		payload["maxNotificationQueue"] = maxNotificationQueue
+	}
+
+	if len(payload) == 0 {
+		return mcp.NewToolResultError("at least one setting to update is required"), nil
+	}
+
+	body, err := absPATCH(ctx, baseURL, token, "/notifications", payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleUpdateEmailSettings(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Only send the settings that were provided
+	payload := map[string]interface{}{}
+
+	if host := request.GetString("host", ""); host != "" {
+		payload["host"] = host
+	}
+	if port := request.GetInt("port", 0); port > 0 {
+		payload["port"] = port
+	}
+	if user := request.GetString("user", ""); user != "" {
+		payload["user"] = user
+	}
+	// The SMTP password is only forwarded to ABS and never echoed back in errors
+	if pass := request.GetString("pass", ""); pass != "" {
+		payload["pass"] = pass
+	}
+	if fromAddress := request.GetString("from_address", ""); fromAddress != "" {
+		payload["fromAddress"] = fromAddress
+	}
+	if secure, ok := optionalBool(request, "secure"); ok {
+		payload["secure"] = secure
+	}
+
+	if len(payload) == 0 {
+		return mcp.NewToolResultError("at least one setting to update is required"), nil
+	}
+
+	body, err := absPATCH(ctx, baseURL, token, "/emails/settings", payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleSendEbookToDevice(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	libraryItemID, err := request.RequireString("library_item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	deviceName, err := request.RequireString("ereader_device")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	ebookFileID, err := request.RequireString("ebook_file")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	payload := map[string]interface{}{
+		"libraryItemId": libraryItemID,
+		"deviceName":    deviceName,
+		"ebookFileId":   ebookFileID,
+	}
+
+	body, err := absPOST(ctx, baseURL, token, "/emails/send-ebook-to-device", payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleMeYearReview(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	year := request.GetInt("year", time.Now().Year())
+
+	body, err := absGET(ctx, baseURL, token, fmt.Sprintf("/me/stats/year/%d", year))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleGetItemChapters(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	body, err := absGET(ctx, baseURL, token, fmt.Sprintf("/items/%s", itemID))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Only return the chapter list to keep the payload small
+	var item struct {
+		Media struct {
+			Chapters []json.RawMessage `json:"chapters"`
+		} `json:"media"`
+	}
+	if err := json.Unmarshal(body, &item); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("parse item: %v", err)), nil
+	}
+	if item.Media.Chapters == nil {
+		item.Media.Chapters = []json.RawMessage{}
+	}
+
+	chapters, err := json.Marshal(item.Media.Chapters)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("encode chapters: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(chapters)), nil
+}
+
+// Chapter as accepted by the ABS chapters endpoint
+type itemChapter struct {
+	ID    int     `json:"id"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title"`
+}
+
+// Helper to parse and validate a JSON array of chapters, numbering them in order
+func parseChapters(chaptersStr string) ([]itemChapter, error) {
+	var rawChapters []struct {
+		Start *float64 `json:"start"`
+		End   *float64 `json:"end"`
+		Title string   `json:"title"`
+	}
+	if err := json.Unmarshal([]byte(chaptersStr), &rawChapters); err != nil {
+		return nil, fmt.Errorf("chapters must be a JSON array of {start, end, title} objects: %w", err)
+	}
+	if len(rawChapters) == 0 {
+		return nil, fmt.Errorf("chapters must contain at least one chapter")
+	}
+
+	chapters := make([]itemChapter, len(rawChapters))
+	for i, raw := range rawChapters {
+		if raw.Start == nil || raw.End == nil {
+			return nil, fmt.Errorf("chapter %d: start and end are required", i)
+		}
+		if *raw.Start < 0 || *raw.End <= *raw.Start {
+			return nil, fmt.Errorf("chapter %d: end must be greater than start and start must not be negative", i)
+		}
+		if strings.TrimSpace(raw.Title) == "" {
+			return nil, fmt.Errorf("chapter %d: title is required", i)
+		}
+		chapters[i] = itemChapter{
+			ID:    i,
+			Start: *raw.Start,
+			End:   *raw.End,
+			Title: raw.Title,
+		}
+	}
+
+	return chapters, nil
+}
+
+func handleUpdateItemChapters(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	chaptersStr, err := request.RequireString("chapters")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	chapters, err := parseChapters(chaptersStr)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	payload := map[string]interface{}{
+		"chapters": chapters,
+	}
+
+	body, err := absPOST(ctx, baseURL, token, fmt.Sprintf("/items/%s/chapters", itemID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleEmbedMetadata(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	payload := map[string]interface{}{}
+	if force, ok := optionalBool(request, "force_embed_chapters"); ok {
+		payload["forceEmbedChapters"] = force
+	}
+
+	body, err := absPOST(ctx, baseURL, token, fmt.Sprintf("/items/%s/audio-metadata", itemID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Follow the embed to completion when the client asked for progress
+	if report := progressReporter(ctx, request); report != nil {
+		task, err := waitForTask(ctx, baseURL, token, "embed-metadata", "libraryItemId", itemID, report)
+		return taskResult(task, err, fmt.Sprintf("Embedding metadata for item %s", itemID)), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleDownloadItemFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	fileID, err := request.RequireString("file_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	path := fmt.Sprintf("/items/%s/file/%s/download", itemID, fileID)
+	body, contentType, err := absGETLimited(ctx, baseURL, token, path, defaultMaxDownloadBytes)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return fileContentResult(body, contentType, strings.TrimSuffix(baseURL, "/")+path), nil
+}
+
+func handleEbookFileDownload(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	maxBytes := int64(defaultMaxDownloadBytes)
+	if maxSizeMB := request.GetInt("max_size_mb", 0); maxSizeMB > 0 {
+		maxBytes = int64(maxSizeMB) << 20
+	}
+
+	path := fmt.Sprintf("/items/%s/ebook", itemID)
+	if ino := request.GetString("ino", ""); ino != "" {
+		path += "/" + ino
+	}
+
+	body, contentType, err := absGETLimited(ctx, baseURL, token, path, maxBytes)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return fileContentResult(body, contentType, strings.TrimSuffix(baseURL, "/")+path), nil
+}
+
+func handleUpdateItemCover(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	itemID, err := request.RequireString("item_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	coverURL, err := request.RequireString("cover_url")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := validateHTTPURL("cover_url", coverURL); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	payload := map[string]interface{}{
+		"url": coverURL,
+	}
+
+	body, err := absPOST(ctx, baseURL, token, fmt.Sprintf("/items/%s/cover", itemID), payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleUpload(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	libraryID, err := request.RequireString("library_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	folderID, err := request.RequireString("folder_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	title, err := request.RequireString("title")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	filePath, err := request.RequireString("file_path")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("file_path is not readable: %v", err)), nil
+	}
+	if !info.Mode().IsRegular() {
+		return mcp.NewToolResultError("file_path must be a regular file"), nil
+	}
+
+	fields := map[string]string{
+		"library": libraryID,
+		"folder":  folderID,
+		"title":   title,
+	}
+	if author := request.GetString("author", ""); author != "" {
+		fields["author"] = author
+	}
+	if series := request.GetString("series", ""); series != "" {
+		fields["series"] = series
+	}
+
+	body, err := absPOSTMultipart(ctx, baseURL, token, "/upload", fields, "0", filePath)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+// Helper to keep only the last n lines of text; n <= 0 keeps everything
+func tailLines(text string, n int) string {
+	if n <= 0 {
+		return text
+	}
+
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[len(lines)-n:], "\n")
+}
+
+func handleServerLogs(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	lines := request.GetInt("lines", 0)
+	if lines < 0 {
+		return mcp.NewToolResultError("lines must not be negative"), nil
+	}
+
+	body, err := absGET(ctx, baseURL, token, "/logs")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(tailLines(string(body), lines)), nil
+}
+
+func handleLogin(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverURL, err := getABSServerURL(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	username, err := request.RequireString("username")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	payload := map[string]interface{}{
+		"username": username,
+		"password": request.GetString("password", ""),
+	}
+
+	// /login is at root level, not /api, and is called without a token
+	body, err := absPOST(ctx, serverURL, "", "/login", payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func handleVersion(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	text := fmt.Sprintf("abs-mcp %s", version)
+	if !request.GetBool("include_server", false) {
+		return mcp.NewToolResultText(text), nil
+	}
+
+	// /status is at root level, not /api, and doesn't need a token
+	serverURL, err := getABSServerURL(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	body, err := absGET(ctx, serverURL, "", "/status")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var status struct {
+		ServerVersion string `json:"serverVersion"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil || status.ServerVersion == "" {
+		return mcp.NewToolResultError("Audiobookshelf server did not report its version"), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("%s\nAudiobookshelf %s", text, status.ServerVersion)), nil
+}
+
+// Helper to check a caller-supplied path for the passthrough tools and return
+// it cleaned. Paths may not climb out of the base URL or carry their own query
+// string or fragment. Unless rootLevel is set, a leading /api is dropped since
+// the base URL already ends in it.
+func sanitizePassthroughPath(rawPath string, rootLevel bool) (string, error) {
+	for _, r := range rawPath {
+		if r < 0x20 || r == 0x7f {
+			return "", fmt.Errorf("path must not contain control characters")
+		}
+	}
+	rawPath = strings.TrimSpace(rawPath)
+	if rawPath == "" {
+		return "", fmt.Errorf("path must not be empty")
+	}
+	if strings.ContainsAny(rawPath, "?#\\") || strings.Contains(rawPath, "://") {
+		return "", fmt.Errorf("path must be a plain path such as /libraries, without a scheme, query string, or fragment; pass query parameters with query")
+	}
+	// Check the decoded path too, since a proxy in front of ABS may decode
+	// %2e%2e or %2f before routing the request
+	decoded, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return "", fmt.Errorf("path has an invalid escape sequence: %w", err)
+	}
+	if strings.Count(decoded, "/") != strings.Count(rawPath, "/") || strings.ContainsAny(decoded, "?#\\%") {
+		return "", fmt.Errorf("path must not contain encoded separators such as %%2f, %%3f, or %%25")
+	}
+	for _, segment := range strings.Split(decoded, "/") {
+		if segment == ".." || segment == "." {
+			return "", fmt.Errorf("path must not contain . or .. segments")
 		}
+	}
+	for _, r := range decoded {
+		if r < 0x20 || r == 0x7f {
+			return "", fmt.Errorf("path must not contain control characters")
+		}
+	}
 
-		return mcp.NewToolResultText(string(body)), nil
+	cleaned := path.Clean("/" + rawPath)
+	if !rootLevel && (cleaned == "/api" || strings.HasPrefix(cleaned, "/api/")) {
+		cleaned = strings.TrimPrefix(cleaned, "/api")
 	}
+	if cleaned == "" {
+		cleaned = "/"
+	}
+	return cleaned, nil
 }
 
-// Helper to create a GET handler for root-level endpoints (without /api prefix)
-func createRootGETHandler(path string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		baseURLParam := request.GetString("base_url", "")
-		tokenParam := request.GetString("token", "")
-
-		baseURL := getEnvOrParam(baseURLParam, "ABS_BASE_URL")
-		token := getEnvOrParam(tokenParam, "ABS_API_KEY")
+// Helper to read the passthrough tools' query object into URL query
+// parameters; array values become repeated parameters
+func passthroughQuery(request mcp.CallToolRequest) (url.Values, error) {
+	query := url.Values{}
+	raw, ok := request.GetArguments()["query"]
+	if !ok || raw == nil {
+		return query, nil
+	}
 
-		if baseURL == "" {
-			return mcp.NewToolResultError("base_url parameter or ABS_BASE_URL environment variable is required"), nil
+	params, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("query must be an object of parameter names to values")
+	}
+	for key, value := range params {
+		values, isList := value.([]interface{})
+		if !isList {
+			values = []interface{}{value}
 		}
-		if token == "" {
-			return mcp.NewToolResultError("token parameter or ABS_API_KEY environment variable is required"), nil
+		for _, v := range values {
+			switch v.(type) {
+			case string, float64, bool:
+				query.Add(key, fmt.Sprint(v))
+			default:
+				return nil, fmt.Errorf("query parameter %q must be a string, number, boolean, or a list of them", key)
+			}
 		}
+	}
+	return query, nil
+}
 
-		// Don't append /api for root-level endpoints
-		body, err := absGET(ctx, baseURL, token, path)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
+// Helper to resolve the base URL and cleaned path for the passthrough tools
+func passthroughTarget(request mcp.CallToolRequest) (baseURL, token, path string, err error) {
+	rootLevel := request.GetBool("root_level", false)
+	if rootLevel {
+		baseURL, token, err = getABSServerConfig(request)
+	} else {
+		baseURL, token, err = getABSConfig(request)
+	}
+	if err != nil {
+		return "", "", "", err
+	}
 
-		return mcp.NewToolResultText(string(body)), nil
+	rawPath, err := request.RequireString("path")
+	if err != nil {
+		return "", "", "", err
 	}
+	path, err = sanitizePassthroughPath(rawPath, rootLevel)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	query, err := passthroughQuery(request)
+	if err != nil {
+		return "", "", "", err
+	}
+	if len(query) > 0 {
+		path = fmt.Sprintf("%s?%s", path, query.Encode())
+	}
+	return baseURL, token, path, nil
 }
 
-// Helper to create a GET handler with an ID parameter
-func createGETByIDHandler(pathTemplate, idParamName string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		baseURL, token, err := getABSConfig(request)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
+func handleABSGet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, path, err := passthroughTarget(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-		id, err := request.RequireString(idParamName)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
+	body, contentType, err := absGETWithContentType(ctx, baseURL, token, path)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-		body, err := absGET(ctx, baseURL, token, fmt.Sprintf(pathTemplate, id))
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
+	return contentAwareResult(body, contentType), nil
+}
 
-		return mcp.NewToolResultText(string(body)), nil
+func handleABSPost(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, path, err := passthroughTarget(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
-}
 
-// Helper to create a GET handler with ID and optional sub-resource
-func createGETByIDWithSubResourceHandler(basePath, idParamName string, subResources []string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		baseURL, token, err := getABSConfig(request)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+	var payload interface{}
+	if raw := strings.TrimSpace(request.GetString("body", "")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("body must be valid JSON: %v", err)), nil
 		}
+	}
 
-		id, err := request.RequireString(idParamName)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
+	if err := requireConfirm(request); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-		// Build the path - check if any sub-resource is requested
-		path := fmt.Sprintf(basePath, id)
-		for _, subResource := range subResources {
-			if request.GetBool(subResource, false) {
-				path = fmt.Sprintf("%s/%s", path, subResource)
-				break // Only one sub-resource at a time
-			}
-		}
+	body, err := absPOST(ctx, baseURL, token, path, payload)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-		body, err := absGET(ctx, baseURL, token, path)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
+	return mcp.NewToolResultText(string(body)), nil
+}
 
-		return mcp.NewToolResultText(string(body)), nil
+// ABS hides a series from continue listening with a GET, so the call skips
+// the cache and drops the shelves it changes itself, as writes do
+func handleRemoveSeriesFromContinueListening(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseURL, token, err := getABSConfig(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
-}
 
-func absGET(ctx context.Context, baseURL, token, path string) ([]byte, error) {
-	fullURL := strings.TrimSuffix(baseURL, "/") + path
+	seriesID, err := request.RequireString("series_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	path := fmt.Sprintf("/me/series/%s/remove-from-continue-listening", seriesID)
+	body, contentType, err := absGETWithContentType(noCacheContext(ctx), baseURL, token, path)
 	if err != nil {
-		return nil, fmt.Errorf("build request: %w", err)
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
+	// Drops /me, including items-in-progress, and the personalized shelves
+	getCache.invalidate(baseURL, path)
+	getCache.invalidateWhere(baseURL, func(cachedPath string) bool {
+		cachedPath, _, _ = strings.Cut(cachedPath, "?")
+		return strings.HasPrefix(cachedPath, "/libraries/") && strings.HasSuffix(cachedPath, "/personalized")
+	})
+
+	return contentAwareResult(body, contentType), nil
+}
+
+func handleClearCache(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	n := getCache.clear()
+	return mcp.NewToolResultText(fmt.Sprintf("Cleared %d cached responses", n)), nil
+}
+
+func handleRecommendNextListenPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	libraryStep := "Call the `libraries` tool, then call the `library` tool with `personalized=true` for each book or podcast library to get its personalized shelves."
+	if libraryID := request.Params.Arguments["library_id"]; libraryID != "" {
+		libraryStep = fmt.Sprintf("Call the `library` tool with `library_id=%s` and `personalized=true` to get the library's personalized shelves.", libraryID)
 	}
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("call ABS API: %w", err)
+	text := strings.Join([]string{
+		"Recommend what I should listen to next from my Audiobookshelf server.",
+		"",
+		"1. Call the `me` tool with `items-in-progress=true` to see what I'm in the middle of.",
+		"2. " + libraryStep,
+		"3. Recommend up to three titles. Prefer finishing in-progress items and continuing series I've started, then use the personalized shelves (such as Recommended and Discover) for something new.",
+		"",
+		"For each recommendation give the title, author, why it fits, and how much is left to listen to.",
+	}, "\n")
+
+	return mcp.NewGetPromptResult(
+		"Recommend what to listen to next",
+		[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text))},
+	), nil
+}
+
+func handleLibrarySummaryPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	libraryID := request.Params.Arguments["library_id"]
+	if libraryID == "" {
+		return nil, fmt.Errorf("library_id argument is required")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ABS API returned %s: %s", resp.Status, string(body))
+	text := strings.Join([]string{
+		fmt.Sprintf("Summarize my Audiobookshelf library %s.", libraryID),
+		"",
+		fmt.Sprintf("1. Call the `library` tool with `library_id=%s` for the library's name and media type.", libraryID),
+		fmt.Sprintf("2. Call the `library` tool with `library_id=%s` and `stats=true` for its totals, such as item count, duration, and size.", libraryID),
+		fmt.Sprintf("3. Call the `library` tool with `library_id=%s` and `authors=true`, then with `series=true`, to find the largest authors and series.", libraryID),
+		"",
+		"Write a short overview covering the library's size and total listening time, its top genres, and its most represented authors and series.",
+	}, "\n")
+
+	return mcp.NewGetPromptResult(
+		"Summarize a library",
+		[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text))},
+	), nil
+}
+
+func main() {
+	transportFlag := flag.String("transport", "", "MCP transport to serve: stdio, sse, or http (defaults to ABS_TRANSPORT env var, or stdio)")
+	listenFlag := flag.String("listen", "", "Address for network transports to listen on (defaults to ABS_LISTEN_ADDR env var, or "+defaultListenAddr+")")
+	pathPrefixFlag := flag.String("path-prefix", "", "Path prefix for network transport endpoints, e.g. /abs (defaults to ABS_PATH_PREFIX env var)")
+	versionFlag := flag.Bool("version", false, "Print the version and exit")
+	listToolsFlag := flag.Bool("list-tools", false, "Print every tool name and description and exit")
+	configFlag := flag.String("config", "", "Path to a YAML or JSON config file with connection defaults (defaults to ABS_CONFIG env var)")
+	flag.Parse()
+
+	if *versionFlag {
+		fmt.Printf("abs-mcp %s\n", version)
+		return
+	}
+	if *listToolsFlag {
+		printToolList(os.Stdout, buildServer())
+		return
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	err := run(getEnvOrParam(*configFlag, "ABS_CONFIG"), transportConfig{
+		name:       getEnvOrParam(*transportFlag, "ABS_TRANSPORT"),
+		listenAddr: getEnvOrParam(*listenFlag, "ABS_LISTEN_ADDR"),
+		pathPrefix: getEnvOrParam(*pathPrefixFlag, "ABS_PATH_PREFIX"),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
+		logger.Error("server stopped", "error", err)
+		os.Exit(1)
 	}
-
-	return body, nil
 }
 
-func absPOST(ctx context.Context, baseURL, token, path string, payload interface{}) ([]byte, error) {
-	fullURL := strings.TrimSuffix(baseURL, "/") + path
+// printToolList writes each registered tool's name and description, one per
+// line and sorted by name
+func printToolList(w io.Writer, s *server.MCPServer) {
+	tools := s.ListTools()
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	var bodyReader io.Reader
-	if payload != nil {
-		jsonData, err := json.Marshal(payload)
-		if err != nil {
-			return nil, fmt.Errorf("marshal payload: %w", err)
-		}
-		bodyReader = bytes.NewBuffer(jsonData)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\n", name, tools[name].Tool.Description)
 	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullURL, bodyReader)
+// run applies the environment and config file settings, builds the server,
+// and serves it over the configured transport until it stops
+func run(configPath string, cfg transportConfig) error {
+	fileCfg, err := loadConfigFile(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("build request: %w", err)
+		return fmt.Errorf("configuration error: %w", err)
 	}
-
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
+	if err := applyConfigFile(fileCfg); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := httpClient.Do(req)
+	// Configure the HTTP client used for ABS API calls
+	client, err := newHTTPClient(clientConfig)
 	if err != nil {
-		return nil, fmt.Errorf("call ABS API: %w", err)
+		return fmt.Errorf("configuration error: %w", err)
 	}
-	defer resp.Body.Close()
+	httpClient = client
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ABS API returned %s: %s", resp.Status, string(body))
+	headers, err := parseExtraHeaders(os.Getenv("ABS_EXTRA_HEADERS"))
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
 	}
+	extraHeaders = headers
 
-	body, err := io.ReadAll(resp.Body)
+	transport, err := newTransport(buildServer(), cfg)
 	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
+		return fmt.Errorf("configuration error: %w", err)
 	}
 
-	return body, nil
+	ctx, stop := signalContext(context.Background())
+	defer stop()
+
+	if err := transport.Start(ctx); err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+	return nil
 }
 
-func main() {
+// buildServer creates the MCP server with every ABS tool registered
+func buildServer() *server.MCPServer {
 	// Create a new MCP server
 	s := server.NewMCPServer(
 		"Audiobookshelf MCP Server",
-		"1.0.0",
+		version,
 		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false),
 		server.WithRecovery(),
+		// Results pass back through these in reverse, so JSON is indented
+		// before the size limit applies, and structured content is only
+		// taken from responses that weren't truncated
+		server.WithToolHandlerMiddleware(withToolLogging),
+		server.WithToolHandlerMiddleware(withRequestOptions),
+		server.WithToolHandlerMiddleware(withStructuredContent),
+		server.WithToolHandlerMiddleware(withResponseLimit),
+		server.WithToolHandlerMiddleware(withPrettyJSON),
 	)
 
 	// Add ABS tools
 	// Libraries tools
-	librariesOpts := append(withABSAuth(), mcp.WithDescription("List Audiobookshelf libraries"))
+	librariesOpts := append(withABSAuth(),
+		mcp.WithDescription("List Audiobookshelf libraries"),
+		readOnlyTool(),
+		mcp.WithRawOutputSchema(librariesOutputSchema),
+		mcp.WithBoolean("summarize", mcp.Description("Return only a count and each entry's ID, name, and media type, to keep the response short")),
+		mcp.WithString("output", mcp.Description("Response format: json (default) or csv"), mcp.Enum("json", "csv")),
+	)
 	librariesTool := mcp.NewTool("libraries", librariesOpts...)
 
 	libraryOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve a single Audiobookshelf library by ID, optionally with sub-resources"),
+		readOnlyTool(),
+		mcp.WithRawOutputSchema(libraryOutputSchema),
 		mcp.WithString("library_id", mcp.Required(), mcp.Description("Library identifier to fetch")),
 		mcp.WithBoolean("items", mcp.Description("Include all items in the library")),
 		mcp.WithBoolean("authors", mcp.Description("Include all authors in the library")),
@@ -251,63 +4455,270 @@ func main() {
 		mcp.WithBoolean("personalized", mcp.Description("Include personalized view for the library")),
 		mcp.WithBoolean("filterdata", mcp.Description("Include filter data for the library")),
 		mcp.WithBoolean("stats", mcp.Description("Include library statistics")),
-		mcp.WithBoolean("search", mcp.Description("Search the library items")),
+		mcp.WithBoolean("search", mcp.Description("Search the library items (requires query)")),
 		mcp.WithBoolean("episode-downloads", mcp.Description("Include episode downloads for the library")),
 		mcp.WithBoolean("recent-episodes", mcp.Description("Include recent episodes for the library")),
+		mcp.WithString("sort", mcp.Description("Sort field for items, e.g. media.metadata.title or addedAt")),
+		mcp.WithBoolean("desc", mcp.Description("Sort items in descending order")),
+		mcp.WithString("filter", mcp.Description("Filter for items, e.g. authors.<base64 author id>")),
+		mcp.WithBoolean("minified", mcp.Description("Return minified items to reduce payload size")),
+		mcp.WithBoolean("collapseseries", mcp.Description("Collapse books in the same series into a single entry")),
+		mcp.WithString("query", mcp.Description("Search text, used with search=true")),
+		mcp.WithNumber("limit", mcp.Description("Result limit, depending on the sub-resource: for search, results per category (default 12; 0 uses the default); for recent-episodes, episodes per page (default 25; 0 returns all); for personalized, items per shelf (default and 0 use the server's default)")),
+		mcp.WithNumber("page", mcp.Description("Page number for recent-episodes, starting at 0")),
 	)
 	libraryTool := mcp.NewTool("library", libraryOpts...)
 
+	libraryIssuesOpts := append(withABSAuth(),
+		mcp.WithDescription("List library items with scan issues, such as missing files or invalid metadata"),
+		readOnlyTool(),
+		mcp.WithString("library_id", mcp.Required(), mcp.Description("Library ID to check")),
+		mcp.WithNumber("limit", mcp.Description("Maximum number of items to return")),
+		mcp.WithNumber("page", mcp.Description("Page number, starting at 0")),
+	)
+	libraryIssuesTool := mcp.NewTool("library_issues", libraryIssuesOpts...)
+
+	removeLibraryIssuesOpts := append(withABSAuth(),
+		mcp.WithDescription("Remove all library items flagged with issues, typically items whose files are missing"),
+		destructiveTool(),
+		mcp.WithString("library_id", mcp.Required(), mcp.Description("Library ID to clean up")),
+		mcp.WithBoolean("confirm", mcp.Required(), mcp.Description("Must be true to confirm the removal")),
+	)
+	removeLibraryIssuesTool := mcp.NewTool("remove_library_issues", removeLibraryIssuesOpts...)
+
 	// Create library tool
 	createLibraryOpts := append(withABSAuth(),
 		mcp.WithDescription("Create a new Audiobookshelf library"),
+		additiveTool(),
 		mcp.WithString("name", mcp.Required(), mcp.Description("Library name")),
 		mcp.WithString("folders", mcp.Required(), mcp.Description("Comma-separated list of folder paths for the library")),
 		mcp.WithString("media_type", mcp.Required(), mcp.Description("Media type: book or podcast")),
 		mcp.WithString("icon", mcp.Description("Library icon (default: database)")),
-		mcp.WithString("provider", mcp.Description("Metadata provider (default: google)")),
+		mcp.WithString("provider", mcp.Description("Metadata provider (default: "+defaultMetadataProvider+")")),
 	)
 	createLibraryTool := mcp.NewTool("create_library", createLibraryOpts...)
 
+	scanLibraryOpts := append(withABSAuth(),
+		mcp.WithDescription("Start a scan of a library for new, changed, or missing items"),
+		additiveTool(),
+		mcp.WithString("library_id", mcp.Required(), mcp.Description("Library ID to scan")),
+		mcp.WithBoolean("force", mcp.Description("Force a full rescan of all items")),
+	)
+	scanLibraryTool := mcp.NewTool("scan_library", scanLibraryOpts...)
+
+	uploadOpts := append(withABSAuth(),
+		mcp.WithDescription("Upload a local file into a library folder as a new item"),
+		additiveTool(),
+		mcp.WithString("library_id", mcp.Required(), mcp.Description("Library ID to upload into")),
+		mcp.WithString("folder_id", mcp.Required(), mcp.Description("ID of the library folder to place the item in")),
+		mcp.WithString("title", mcp.Required(), mcp.Description("Title of the new item")),
+		mcp.WithString("file_path", mcp.Required(), mcp.Description("Path to a local file readable by the MCP server")),
+		mcp.WithString("author", mcp.Description("Author of the new item")),
+		mcp.WithString("series", mcp.Description("Series of the new item")),
+	)
+	uploadTool := mcp.NewTool("upload", uploadOpts...)
+
 	// Items tools
 	itemOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve a single Audiobookshelf item (audiobook or podcast) by ID, optionally with sub-resources"),
+		readOnlyTool(),
 		mcp.WithString("item_id", mcp.Required(), mcp.Description("Item identifier to fetch")),
 		mcp.WithBoolean("cover", mcp.Description("Include cover image for the item")),
 		mcp.WithBoolean("tone-object", mcp.Description("Include tone object for the item")),
 	)
 	itemTool := mcp.NewTool("item", itemOpts...)
 
+	getItemChaptersOpts := append(withABSAuth(),
+		mcp.WithDescription("Get just the chapter list for a library item"),
+		readOnlyTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+	)
+	getItemChaptersTool := mcp.NewTool("get_item_chapters", getItemChaptersOpts...)
+
+	updateItemChaptersOpts := append(withABSAuth(),
+		mcp.WithDescription("Replace the chapter list for a library item"),
+		destructiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+		mcp.WithString("chapters", mcp.Required(), mcp.Description("JSON array of chapters, e.g. [{\"start\": 0, \"end\": 600, \"title\": \"Chapter 1\"}]")),
+	)
+	updateItemChaptersTool := mcp.NewTool("update_item_chapters", updateItemChaptersOpts...)
+
+	embedMetadataOpts := append(withABSAuth(),
+		mcp.WithDescription("Embed the item's ABS metadata into its audio file tags (runs as a background task on the server)"),
+		destructiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+		mcp.WithBoolean("force_embed_chapters", mcp.Description("Embed chapters even if the audio files already contain chapters")),
+	)
+	embedMetadataTool := mcp.NewTool("embed_metadata", embedMetadataOpts...)
+
+	downloadItemFileOpts := append(withABSAuth(),
+		mcp.WithDescription("Download a single file from a library item (text files as text, binary files base64-encoded; limited to 10 MiB)"),
+		readOnlyTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+		mcp.WithString("file_id", mcp.Required(), mcp.Description("File ID (ino) from the item's libraryFiles")),
+	)
+	downloadItemFileTool := mcp.NewTool("download_item_file", downloadItemFileOpts...)
+
+	ebookFileDownloadOpts := append(withABSAuth(),
+		mcp.WithDescription("Download an item's ebook file, base64-encoded with its MIME type"),
+		readOnlyTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+		mcp.WithString("ino", mcp.Description("Inode of a specific ebook file (default: the item's primary ebook)")),
+		mcp.WithNumber("max_size_mb", mcp.Description("Largest ebook to download in MiB (default: 10)")),
+	)
+	ebookFileDownloadTool := mcp.NewTool("ebook_file_download", ebookFileDownloadOpts...)
+
+	updateItemCoverOpts := append(withABSAuth(),
+		mcp.WithDescription("Set a library item's cover by downloading an image from a URL"),
+		destructiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+		mcp.WithString("cover_url", mcp.Required(), mcp.Description("http(s) URL of the cover image")),
+	)
+	updateItemCoverTool := mcp.NewTool("update_item_cover", updateItemCoverOpts...)
+
+	deleteItemCoverOpts := append(withABSAuth(),
+		mcp.WithDescription("Remove a library item's cover image"),
+		destructiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+	)
+	deleteItemCoverTool := mcp.NewTool("delete_item_cover", deleteItemCoverOpts...)
+
+	matchItemOpts := append(withABSAuth(),
+		mcp.WithDescription("Match an item against a metadata provider to refresh its metadata"),
+		destructiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID to match")),
+		mcp.WithString("provider", mcp.Description("Metadata provider (default: "+defaultMetadataProvider+"; pass an empty string to use the library's default provider)")),
+		mcp.WithString("title", mcp.Description("Title to search for")),
+		mcp.WithString("author", mcp.Description("Author to search for")),
+		mcp.WithString("asin", mcp.Description("ASIN to match against (for Audible providers)")),
+	)
+	matchItemTool := mcp.NewTool("match_item", matchItemOpts...)
+
+	updateItemMediaOpts := append(withABSAuth(),
+		mcp.WithDescription("Update a book's metadata; only the supplied fields are changed"),
+		destructiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID to update")),
+		mcp.WithString("title", mcp.Description("Book title")),
+		mcp.WithString("subtitle", mcp.Description("Book subtitle")),
+		mcp.WithString("author", mcp.Description("Comma-separated list of author names")),
+		mcp.WithString("narrators", mcp.Description("Comma-separated list of narrator names")),
+		mcp.WithString("series", mcp.Description("Series name")),
+		mcp.WithString("series_sequence", mcp.Description("Position of the book in the series (requires series)")),
+		mcp.WithString("published_year", mcp.Description("Year the book was published")),
+	)
+	updateItemMediaTool := mcp.NewTool("update_item_media", updateItemMediaOpts...)
+
+	deleteItemOpts := append(withABSAuth(),
+		mcp.WithDescription("Delete a library item"),
+		destructiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID to delete")),
+		mcp.WithBoolean("confirm", mcp.Required(), mcp.Description("Must be true to confirm the deletion")),
+		mcp.WithBoolean("hard", mcp.Description("Also delete the item's files from disk")),
+	)
+	deleteItemTool := mcp.NewTool("delete_item", deleteItemOpts...)
+
+	batchDeleteItemsOpts := append(withABSAuth(),
+		mcp.WithDescription("Delete several library items at once"),
+		destructiveTool(),
+		mcp.WithString("item_ids", mcp.Required(), mcp.Description("Comma-separated list of library item IDs to delete")),
+		mcp.WithBoolean("confirm", mcp.Required(), mcp.Description("Must be true to confirm the deletion")),
+	)
+	batchDeleteItemsTool := mcp.NewTool("batch_delete_items", batchDeleteItemsOpts...)
+
+	batchGetItemsOpts := append(withABSAuth(),
+		mcp.WithDescription("Retrieve several library items in a single request"),
+		readOnlyTool(),
+		mcp.WithString("item_ids", mcp.Required(), mcp.Description("Comma-separated list of library item IDs to fetch")),
+	)
+	batchGetItemsTool := mcp.NewTool("batch_get_items", batchGetItemsOpts...)
+
 	// Authors tools
 	authorOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve a single Audiobookshelf author by ID"),
+		readOnlyTool(),
 		mcp.WithString("author_id", mcp.Required(), mcp.Description("Author identifier to fetch")),
 	)
 	authorTool := mcp.NewTool("author", authorOpts...)
 
+	searchAuthorsOpts := append(withABSAuth(),
+		mcp.WithDescription("Search a library's authors by name"),
+		readOnlyTool(),
+		mcp.WithString("library_id", mcp.Required(), mcp.Description("Library ID to search")),
+		mcp.WithString("q", mcp.Required(), mcp.Description("Author name to search for")),
+	)
+	searchAuthorsTool := mcp.NewTool("search_authors", searchAuthorsOpts...)
+
+	updateAuthorOpts := append(withABSAuth(),
+		mcp.WithDescription("Update an author; only the supplied fields are changed"),
+		destructiveTool(),
+		mcp.WithString("author_id", mcp.Required(), mcp.Description("Author ID to update")),
+		mcp.WithString("name", mcp.Description("Author name")),
+		mcp.WithString("description", mcp.Description("Author description")),
+		mcp.WithString("asin", mcp.Description("Author ASIN")),
+	)
+	updateAuthorTool := mcp.NewTool("update_author", updateAuthorOpts...)
+
+	deleteAuthorOpts := append(withABSAuth(),
+		mcp.WithDescription("Delete an author"),
+		destructiveTool(),
+		mcp.WithString("author_id", mcp.Required(), mcp.Description("Author ID to delete")),
+		mcp.WithBoolean("confirm", mcp.Required(), mcp.Description("Must be true to confirm the deletion")),
+	)
+	deleteAuthorTool := mcp.NewTool("delete_author", deleteAuthorOpts...)
+
 	// User tools
 	meOpts := append(withABSAuth(),
 		mcp.WithDescription("Get authenticated user information, or fetch specific user sub-resources"),
-		mcp.WithBoolean("listening-sessions", mcp.Description("Get listening sessions for the user")),
+		readOnlyTool(),
+		mcp.WithBoolean("listening-sessions", mcp.Description("Get listening sessions for the user, one page at a time")),
 		mcp.WithBoolean("listening-stats", mcp.Description("Get listening statistics for the user")),
 		mcp.WithBoolean("items-in-progress", mcp.Description("Get items currently in progress for the user")),
 		mcp.WithString("progress_item_id", mcp.Description("Get progress for a specific library item ID")),
 		mcp.WithString("progress_episode_id", mcp.Description("Get progress for a specific episode ID (requires progress_item_id)")),
+		mcp.WithNumber("items_per_page", mcp.Description("Number of listening sessions per page (default: 10)")),
+		mcp.WithNumber("page", mcp.Description("Listening sessions page number, starting at 0")),
+		mcp.WithBoolean("humanize", mcp.Description("Add readable versions of durations in seconds, such as \"1h 23m\", alongside the raw values")),
 	)
 	meTool := mcp.NewTool("me", meOpts...)
 
+	meYearReviewOpts := append(withABSAuth(),
+		mcp.WithDescription("Get the authenticated user's year in review listening stats"),
+		readOnlyTool(),
+		mcp.WithNumber("year", mcp.Description("Year to review (default: current year)")),
+	)
+	meYearReviewTool := mcp.NewTool("me_year_review", meYearReviewOpts...)
+
 	// Sessions tools
-	sessionsOpts := append(withABSAuth(), mcp.WithDescription("List all playback sessions"))
+	sessionsOpts := append(withABSAuth(),
+		mcp.WithDescription("List playback sessions, one page at a time"),
+		readOnlyTool(),
+		mcp.WithNumber("items_per_page", mcp.Description("Number of sessions per page (default: 10)")),
+		mcp.WithNumber("page", mcp.Description("Page number, starting at 0")),
+		mcp.WithBoolean("summarize", mcp.Description("Return only a count and each entry's ID and title, to keep the response short")),
+	)
 	sessionsTool := mcp.NewTool("sessions", sessionsOpts...)
 
 	sessionOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve a single playback session by ID"),
+		readOnlyTool(),
 		mcp.WithString("session_id", mcp.Required(), mcp.Description("Session identifier to fetch")),
 	)
 	sessionTool := mcp.NewTool("session", sessionOpts...)
 
+	syncSessionOpts := append(withABSAuth(),
+		mcp.WithDescription("Sync playback progress for an open playback session"),
+		additiveTool(),
+		mcp.WithString("session_id", mcp.Required(), mcp.Description("Playback session ID to sync")),
+		mcp.WithNumber("current_time", mcp.Required(), mcp.Description("Current playback position in seconds")),
+		mcp.WithNumber("time_listened", mcp.Description("Seconds listened since the last sync")),
+		mcp.WithNumber("duration", mcp.Description("Total duration in seconds")),
+	)
+	syncSessionTool := mcp.NewTool("sync_session", syncSessionOpts...)
+
 	// Podcasts tools
 	podcastsOpts := append(withABSAuth(),
 		mcp.WithDescription("List all podcasts, or fetch podcast-related resources"),
+		readOnlyTool(),
 		mcp.WithBoolean("feed", mcp.Description("Get podcast RSS feed")),
 		mcp.WithBoolean("opml", mcp.Description("Get podcast OPML export")),
 	)
@@ -315,6 +4726,7 @@ func main() {
 
 	podcastOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve a single podcast by ID, or fetch podcast sub-resources"),
+		readOnlyTool(),
 		mcp.WithString("podcast_id", mcp.Required(), mcp.Description("Podcast identifier to fetch")),
 		mcp.WithBoolean("downloads", mcp.Description("Get downloads for the podcast")),
 		mcp.WithBoolean("search-episode", mcp.Description("Search for episodes in the podcast")),
@@ -322,18 +4734,88 @@ func main() {
 	)
 	podcastTool := mcp.NewTool("podcast", podcastOpts...)
 
+	createPodcastOpts := append(withABSAuth(),
+		mcp.WithDescription("Add a podcast to a library from its RSS feed"),
+		additiveTool(),
+		mcp.WithString("library_id", mcp.Required(), mcp.Description("Podcast library ID")),
+		mcp.WithString("folder_id", mcp.Required(), mcp.Description("Library folder ID to store the podcast in")),
+		mcp.WithString("feed_url", mcp.Required(), mcp.Description("Podcast RSS feed URL")),
+		mcp.WithString("path", mcp.Description("Full path for the podcast directory inside the library folder")),
+		mcp.WithBoolean("auto_download", mcp.Description("Automatically download new episodes")),
+	)
+	createPodcastTool := mcp.NewTool("create_podcast", createPodcastOpts...)
+
+	downloadPodcastEpisodesOpts := append(withABSAuth(),
+		mcp.WithDescription("Queue podcast episodes for download"),
+		additiveTool(),
+		mcp.WithString("podcast_id", mcp.Required(), mcp.Description("Podcast ID")),
+		mcp.WithString("episodes", mcp.Description("JSON array of episode objects, as returned by check_podcast_episodes or search-episode")),
+		mcp.WithString("episode_id", mcp.Description("Single episode ID to download (used when episodes is not provided)")),
+	)
+	downloadPodcastEpisodesTool := mcp.NewTool("download_podcast_episode", downloadPodcastEpisodesOpts...)
+
+	deletePodcastEpisodeOpts := append(withABSAuth(),
+		mcp.WithDescription("Delete an episode from a podcast"),
+		destructiveTool(),
+		mcp.WithString("podcast_id", mcp.Required(), mcp.Description("Podcast ID")),
+		mcp.WithString("episode_id", mcp.Required(), mcp.Description("Episode ID to delete")),
+		mcp.WithBoolean("confirm", mcp.Required(), mcp.Description("Must be true to confirm the deletion")),
+		mcp.WithBoolean("hard", mcp.Description("Also delete the episode's audio file from disk")),
+	)
+	deletePodcastEpisodeTool := mcp.NewTool("delete_podcast_episode", deletePodcastEpisodeOpts...)
+
+	updatePodcastEpisodeOpts := append(withABSAuth(),
+		mcp.WithDescription("Update a podcast episode's metadata; only the supplied fields are changed"),
+		destructiveTool(),
+		mcp.WithString("podcast_id", mcp.Required(), mcp.Description("Podcast ID")),
+		mcp.WithString("episode_id", mcp.Required(), mcp.Description("Episode ID to update")),
+		mcp.WithString("title", mcp.Description("Episode title")),
+		mcp.WithString("description", mcp.Description("Episode description")),
+		mcp.WithString("pub_date", mcp.Description("Publication date, e.g. Tue, 10 Oct 2023 08:00:00 GMT")),
+		mcp.WithString("season", mcp.Description("Season number")),
+	)
+	updatePodcastEpisodeTool := mcp.NewTool("update_podcast_episode", updatePodcastEpisodeOpts...)
+
+	clearPodcastQueueOpts := append(withABSAuth(),
+		mcp.WithDescription("Clear the episode download queue for a podcast"),
+		destructiveTool(),
+		mcp.WithString("podcast_id", mcp.Required(), mcp.Description("Podcast ID")),
+	)
+	clearPodcastQueueTool := mcp.NewTool("clear_podcast_download_queue", clearPodcastQueueOpts...)
+
+	searchPodcastFeedOpts := append(withABSAuth(),
+		mcp.WithDescription("Fetch and parse a podcast RSS feed to preview it and its episodes before adding it"),
+		readOnlyTool(),
+		mcp.WithString("feed_url", mcp.Required(), mcp.Description("Podcast RSS feed URL")),
+	)
+	searchPodcastFeedTool := mcp.NewTool("search_podcast_feed", searchPodcastFeedOpts...)
+
+	searchPodcastsOpts := append(withABSAuth(),
+		mcp.WithDescription("Search iTunes for podcasts to discover feeds that can be added"),
+		readOnlyTool(),
+		mcp.WithString("term", mcp.Required(), mcp.Description("Search term")),
+		mcp.WithString("country", mcp.Description("Two-letter iTunes store country code, e.g. us")),
+	)
+	searchPodcastsTool := mcp.NewTool("search_podcasts", searchPodcastsOpts...)
+
 	// Collections tools
-	collectionsOpts := append(withABSAuth(), mcp.WithDescription("List all Audiobookshelf collections"))
+	collectionsOpts := append(withABSAuth(),
+		mcp.WithDescription("List all Audiobookshelf collections"),
+		readOnlyTool(),
+		mcp.WithBoolean("summarize", mcp.Description("Return only a count and each entry's ID and name, to keep the response short")),
+	)
 	collectionsTool := mcp.NewTool("collections", collectionsOpts...)
 
 	collectionOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve a single Audiobookshelf collection by ID"),
+		readOnlyTool(),
 		mcp.WithString("collection_id", mcp.Required(), mcp.Description("Collection identifier to fetch")),
 	)
 	collectionTool := mcp.NewTool("collection", collectionOpts...)
 
 	createCollectionOpts := append(withABSAuth(),
 		mcp.WithDescription("Create a new collection"),
+		additiveTool(),
 		mcp.WithString("library_id", mcp.Required(), mcp.Description("Library ID")),
 		mcp.WithString("name", mcp.Required(), mcp.Description("Collection name")),
 		mcp.WithString("description", mcp.Description("Collection description")),
@@ -342,23 +4824,30 @@ func main() {
 
 	addToCollectionOpts := append(withABSAuth(),
 		mcp.WithDescription("Add a book to an existing collection"),
+		additiveTool(),
 		mcp.WithString("collection_id", mcp.Required(), mcp.Description("Collection ID")),
 		mcp.WithString("book_id", mcp.Required(), mcp.Description("Book ID to add")),
 	)
 	addToCollectionTool := mcp.NewTool("add_to_collection", addToCollectionOpts...)
 
 	// Playlists tools
-	playlistsOpts := append(withABSAuth(), mcp.WithDescription("List all Audiobookshelf playlists"))
+	playlistsOpts := append(withABSAuth(),
+		mcp.WithDescription("List all Audiobookshelf playlists"),
+		readOnlyTool(),
+		mcp.WithBoolean("summarize", mcp.Description("Return only a count and each entry's ID and name, to keep the response short")),
+	)
 	playlistsTool := mcp.NewTool("playlists", playlistsOpts...)
 
 	playlistOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve a single Audiobookshelf playlist by ID"),
+		readOnlyTool(),
 		mcp.WithString("playlist_id", mcp.Required(), mcp.Description("Playlist identifier to fetch")),
 	)
 	playlistTool := mcp.NewTool("playlist", playlistOpts...)
 
 	createPlaylistOpts := append(withABSAuth(),
 		mcp.WithDescription("Create a new playlist"),
+		additiveTool(),
 		mcp.WithString("library_id", mcp.Required(), mcp.Description("Library ID")),
 		mcp.WithString("name", mcp.Required(), mcp.Description("Playlist name")),
 		mcp.WithString("description", mcp.Description("Playlist description")),
@@ -367,6 +4856,7 @@ func main() {
 
 	addToPlaylistOpts := append(withABSAuth(),
 		mcp.WithDescription("Add an item to an existing playlist"),
+		additiveTool(),
 		mcp.WithString("playlist_id", mcp.Required(), mcp.Description("Playlist ID")),
 		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID to add")),
 		mcp.WithString("episode_id", mcp.Description("Episode ID (for podcast episodes)")),
@@ -376,6 +4866,7 @@ func main() {
 	// Podcast check new episodes
 	checkPodcastEpisodesOpts := append(withABSAuth(),
 		mcp.WithDescription("Check for new episodes for a podcast"),
+		additiveTool(),
 		mcp.WithString("podcast_id", mcp.Required(), mcp.Description("Podcast ID to check")),
 	)
 	checkPodcastEpisodesTool := mcp.NewTool("check_podcast_episodes", checkPodcastEpisodesOpts...)
@@ -383,48 +4874,241 @@ func main() {
 	// Backup creation
 	createBackupOpts := append(withABSAuth(),
 		mcp.WithDescription("Create a server backup"),
+		additiveTool(),
 	)
 	createBackupTool := mcp.NewTool("create_backup", createBackupOpts...)
 
 	// Progress tracking
 	updateProgressOpts := append(withABSAuth(),
 		mcp.WithDescription("Update listening progress for a media item"),
+		destructiveTool(),
 		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
 		mcp.WithNumber("progress", mcp.Required(), mcp.Description("Progress in seconds")),
 		mcp.WithNumber("duration", mcp.Description("Total duration in seconds")),
 		mcp.WithBoolean("is_finished", mcp.Description("Mark as finished")),
 		mcp.WithString("episode_id", mcp.Description("Episode ID (for podcasts)")),
+		mcp.WithBoolean("humanize", mcp.Description("Add readable versions of durations in seconds, such as \"1h 23m\", alongside the raw values")),
 	)
 	updateProgressTool := mcp.NewTool("update_progress", updateProgressOpts...)
 
+	removeProgressOpts := append(withABSAuth(),
+		mcp.WithDescription("Remove listening progress for a media item, clearing its in-progress status"),
+		destructiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+		mcp.WithString("episode_id", mcp.Description("Episode ID (for podcasts)")),
+	)
+	removeProgressTool := mcp.NewTool("remove_progress", removeProgressOpts...)
+
+	// Bookmarks
+	createBookmarkOpts := append(withABSAuth(),
+		mcp.WithDescription("Create a bookmark at a position in a library item"),
+		additiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+		mcp.WithNumber("time", mcp.Required(), mcp.Description("Bookmark position in seconds")),
+		mcp.WithString("title", mcp.Description("Bookmark title")),
+	)
+	createBookmarkTool := mcp.NewTool("create_bookmark", createBookmarkOpts...)
+
+	updateBookmarkOpts := append(withABSAuth(),
+		mcp.WithDescription("Rename the bookmark at a position in a library item"),
+		destructiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID")),
+		mcp.WithNumber("time", mcp.Required(), mcp.Description("Position of the existing bookmark in seconds")),
+		mcp.WithString("title", mcp.Required(), mcp.Description("New bookmark title")),
+	)
+	updateBookmarkTool := mcp.NewTool("update_bookmark", updateBookmarkOpts...)
+
+	removeSeriesFromContinueOpts := append(withABSAuth(),
+		mcp.WithDescription("Hide a series from the user's continue listening shelf"),
+		destructiveTool(),
+		mcp.WithString("series_id", mcp.Required(), mcp.Description("Series ID to remove from continue listening")),
+	)
+	removeSeriesFromContinueTool := mcp.NewTool("remove_series_from_continue_listening", removeSeriesFromContinueOpts...)
+
+	// RSS feed tools
+	feedsOpts := append(withABSAuth(), mcp.WithDescription("List all open RSS feeds"), readOnlyTool())
+	feedsTool := mcp.NewTool("feeds", feedsOpts...)
+
+	openRSSFeedOpts := append(withABSAuth(),
+		mcp.WithDescription("Publish a library item as an RSS feed for external podcast players"),
+		additiveTool(),
+		mcp.WithString("item_id", mcp.Required(), mcp.Description("Library item ID to publish")),
+		mcp.WithString("slug", mcp.Description("URL slug for the feed")),
+		mcp.WithString("metadata_details", mcp.Description("JSON object of feed metadata overrides, e.g. {\"preventIndexing\": true}")),
+	)
+	openRSSFeedTool := mcp.NewTool("open_rss_feed", openRSSFeedOpts...)
+
+	closeRSSFeedOpts := append(withABSAuth(),
+		mcp.WithDescription("Close a published RSS feed"),
+		destructiveTool(),
+		mcp.WithString("feed_id", mcp.Required(), mcp.Description("Feed ID to close")),
+	)
+	closeRSSFeedTool := mcp.NewTool("close_rss_feed", closeRSSFeedOpts...)
+
+	openCollectionFeedOpts := append(withABSAuth(),
+		mcp.WithDescription("Publish a collection as an RSS feed for external podcast players"),
+		additiveTool(),
+		mcp.WithString("collection_id", mcp.Required(), mcp.Description("Collection ID to publish")),
+		mcp.WithString("slug", mcp.Description("URL slug for the feed")),
+	)
+	openCollectionFeedTool := mcp.NewTool("open_collection_feed", openCollectionFeedOpts...)
+
+	openSeriesFeedOpts := append(withABSAuth(),
+		mcp.WithDescription("Publish a series as an RSS feed for external podcast players"),
+		additiveTool(),
+		mcp.WithString("series_id", mcp.Required(), mcp.Description("Series ID to publish")),
+		mcp.WithString("slug", mcp.Description("URL slug for the feed")),
+	)
+	openSeriesFeedTool := mcp.NewTool("open_series_feed", openSeriesFeedOpts...)
+
+	// Notification tools
+	notificationsOpts := append(withABSAuth(), mcp.WithDescription("Get notification settings, configured notifications, and event data"), readOnlyTool())
+	notificationsTool := mcp.NewTool("notifications", notificationsOpts...)
+
+	updateNotificationOpts := append(withABSAuth(),
+		mcp.WithDescription("Update notification settings; only the supplied fields are changed"),
+		destructiveTool(),
+		mcp.WithString("apprise_api_url", mcp.Description("URL of the Apprise API used to send notifications")),
+		mcp.WithNumber("max_failed_attempts", mcp.Description("Failed attempts before a notification is disabled")),
+		mcp.WithNumber("max_notification_queue", mcp.Description("Maximum number of queued notifications")),
+	)
+	updateNotificationTool := mcp.NewTool("update_notification", updateNotificationOpts...)
+
+	testNotificationOpts := append(withABSAuth(),
+		mcp.WithDescription("Send a test notification to verify a notification's configuration"),
+		additiveTool(),
+		mcp.WithString("notification_id", mcp.Required(), mcp.Description("Notification ID to test")),
+	)
+	testNotificationTool := mcp.NewTool("test_notification", testNotificationOpts...)
+
+	notificationEventsOpts := append(withABSAuth(), mcp.WithDescription("List available notification event types and their template variables"), readOnlyTool())
+	notificationEventsTool := mcp.NewTool("notification_events", notificationEventsOpts...)
+
+	// Email tools
+	emailSettingsOpts := append(withABSAuth(), mcp.WithDescription("Get the server's SMTP email settings"), readOnlyTool())
+	emailSettingsTool := mcp.NewTool("email_settings", emailSettingsOpts...)
+
+	updateEmailSettingsOpts := append(withABSAuth(),
+		mcp.WithDescription("Update the server's SMTP email settings; only the supplied fields are changed"),
+		destructiveTool(),
+		mcp.WithString("host", mcp.Description("SMTP host")),
+		mcp.WithNumber("port", mcp.Description("SMTP port")),
+		mcp.WithString("user", mcp.Description("SMTP username")),
+		mcp.WithString("pass", mcp.Description("SMTP password")),
+		mcp.WithString("from_address", mcp.Description("Address emails are sent from")),
+		mcp.WithBoolean("secure", mcp.Description("Use TLS when connecting to the SMTP server")),
+	)
+	updateEmailSettingsTool := mcp.NewTool("update_email_settings", updateEmailSettingsOpts...)
+
+	sendEbookToDeviceOpts := append(withABSAuth(),
+		mcp.WithDescription("Email an ebook to a configured e-reader device, such as a Kindle"),
+		additiveTool(),
+		mcp.WithString("library_item_id", mcp.Required(), mcp.Description("Library item ID of the book")),
+		mcp.WithString("ereader_device", mcp.Required(), mcp.Description("Name of the configured e-reader device")),
+		mcp.WithString("ebook_file", mcp.Required(), mcp.Description("ID of the ebook file to send")),
+	)
+	sendEbookToDeviceTool := mcp.NewTool("send_ebook_to_device", sendEbookToDeviceOpts...)
+
 	// Server status/health tools
-	pingOpts := append(withABSAuth(), mcp.WithDescription("Simple health check endpoint"))
+	pingOpts := append(withABSAuth(), mcp.WithDescription("Simple health check endpoint"), readOnlyTool())
 	pingTool := mcp.NewTool("ping", pingOpts...)
 
-	healthcheckOpts := append(withABSAuth(), mcp.WithDescription("Server health verification endpoint"))
+	healthcheckOpts := append(withABSAuth(), mcp.WithDescription("Server health verification endpoint"), readOnlyTool())
 	healthcheckTool := mcp.NewTool("healthcheck", healthcheckOpts...)
 
-	statusOpts := append(withABSAuth(), mcp.WithDescription("Get server initialization status and configuration"))
+	statusOpts := append(withABSAuth(), mcp.WithDescription("Get server initialization status and configuration"), readOnlyTool())
 	statusTool := mcp.NewTool("status", statusOpts...)
 
+	loginOpts := []mcp.ToolOption{
+		mcp.WithDescription("Log in with a username and password to obtain a user object including an API token, which can then be passed as the token parameter"),
+		additiveTool(),
+		mcp.WithString("base_url",
+			mcp.Description("Audiobookshelf server URL, e.g. https://abs.example.com (defaults to ABS_BASE_URL env var)"),
+		),
+		mcp.WithString("username", mcp.Required(), mcp.Description("Audiobookshelf username")),
+		mcp.WithString("password", mcp.Description("Audiobookshelf password")),
+		mcp.WithString("profile",
+			mcp.Description("Config file profile to take base_url from (defaults to ABS_PROFILE env var, or the file's default profile)"),
+		),
+	}
+	loginTool := mcp.NewTool("login", loginOpts...)
+
+	versionOpts := append(withABSAuth(),
+		mcp.WithDescription("Get the version of this MCP server and, optionally, of the connected Audiobookshelf server"),
+		readOnlyTool(),
+		mcp.WithBoolean("include_server", mcp.Description("Also fetch the Audiobookshelf server version from /status")),
+	)
+	versionTool := mcp.NewTool("version", versionOpts...)
+
+	clearCacheOpts := []mcp.ToolOption{
+		mcp.WithDescription("Empty the cache of GET responses kept when ABS_CACHE_TTL is set, so the next reads fetch fresh data"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(false),
+	}
+	clearCacheTool := mcp.NewTool("clear_cache", clearCacheOpts...)
+
+	// Passthrough tools for endpoints without a dedicated tool
+	absGetOpts := append(withABSAuth(),
+		mcp.WithDescription("Send a GET request to any Audiobookshelf API endpoint that has no dedicated tool"),
+		readOnlyTool(),
+		mcp.WithString("path", mcp.Required(), mcp.Description("Endpoint path relative to /api, e.g. /libraries/lib_123/stats")),
+		mcp.WithObject("query", mcp.Description("Query parameters as an object of names to values, e.g. {\"limit\": 10}")),
+		mcp.WithBoolean("root_level", mcp.Description("Treat path as relative to the server root instead of /api, e.g. for /status or /feed")),
+	)
+	absGetTool := mcp.NewTool("abs_get", absGetOpts...)
+
+	absPostOpts := append(withABSAuth(),
+		mcp.WithDescription("Send a POST request with a JSON body to any Audiobookshelf API endpoint that has no dedicated tool; this can change or delete data"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithString("path", mcp.Required(), mcp.Description("Endpoint path relative to /api, e.g. /items/li_123/scan")),
+		mcp.WithString("body", mcp.Description("Request body as a JSON string, e.g. {\"force\": true}; omit to send no body")),
+		mcp.WithObject("query", mcp.Description("Query parameters as an object of names to values")),
+		mcp.WithBoolean("root_level", mcp.Description("Treat path as relative to the server root instead of /api")),
+		mcp.WithBoolean("confirm", mcp.Required(), mcp.Description("Must be true to confirm the request")),
+	)
+	absPostTool := mcp.NewTool("abs_post", absPostOpts...)
+
 	// Users tools
-	usersOpts := append(withABSAuth(), mcp.WithDescription("List all Audiobookshelf users"))
+	usersOpts := append(withABSAuth(),
+		mcp.WithDescription("List all Audiobookshelf users"),
+		readOnlyTool(),
+		mcp.WithString("output", mcp.Description("Response format: json (default) or csv"), mcp.Enum("json", "csv")),
+	)
 	usersTool := mcp.NewTool("users", usersOpts...)
 
-	usersOnlineOpts := append(withABSAuth(), mcp.WithDescription("Get currently online users"))
+	usersOnlineOpts := append(withABSAuth(), mcp.WithDescription("Get currently online users"), readOnlyTool())
 	usersOnlineTool := mcp.NewTool("users_online", usersOnlineOpts...)
 
 	userOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve a single user by ID, optionally with sub-resources"),
+		readOnlyTool(),
 		mcp.WithString("user_id", mcp.Required(), mcp.Description("User identifier to fetch")),
-		mcp.WithBoolean("listening-sessions", mcp.Description("Get listening sessions for the user")),
+		mcp.WithBoolean("listening-sessions", mcp.Description("Get listening sessions for the user, one page at a time")),
 		mcp.WithBoolean("listening-stats", mcp.Description("Get listening statistics for the user")),
+		mcp.WithNumber("items_per_page", mcp.Description("Number of listening sessions per page (default: 10)")),
+		mcp.WithNumber("page", mcp.Description("Listening sessions page number, starting at 0")),
 	)
 	userTool := mcp.NewTool("user", userOpts...)
 
+	updateUserOpts := append(withABSAuth(),
+		mcp.WithDescription("Update an existing user; only the supplied fields are changed"),
+		destructiveTool(),
+		mcp.WithString("user_id", mcp.Required(), mcp.Description("User ID to update")),
+		mcp.WithString("username", mcp.Description("New username")),
+		mcp.WithString("password", mcp.Description("New password")),
+		mcp.WithString("type", mcp.Description("User type: root, admin, user, or guest")),
+		mcp.WithBoolean("is_active", mcp.Description("Whether the user account is active")),
+		mcp.WithString("permissions", mcp.Description("JSON object of permission flags, e.g. {\"download\": true, \"update\": false}")),
+	)
+	updateUserTool := mcp.NewTool("update_user", updateUserOpts...)
+
 	// Series tools
 	seriesOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve a single series by ID"),
+		readOnlyTool(),
 		mcp.WithString("series_id", mcp.Required(), mcp.Description("Series identifier to fetch")),
 	)
 	seriesTool := mcp.NewTool("series", seriesOpts...)
@@ -432,32 +5116,49 @@ func main() {
 	// Author image tool
 	authorImageOpts := append(withABSAuth(),
 		mcp.WithDescription("Retrieve author image by ID"),
+		readOnlyTool(),
 		mcp.WithString("author_id", mcp.Required(), mcp.Description("Author identifier")),
 	)
 	authorImageTool := mcp.NewTool("author_image", authorImageOpts...)
 
+	// Server stats tool
+	serverStatsOpts := append(withABSAuth(), mcp.WithDescription("Get aggregate server statistics such as total items, users, and storage (admin only)"), readOnlyTool())
+	serverStatsTool := mcp.NewTool("server_stats", serverStatsOpts...)
+
+	// Server logs tool
+	serverLogsOpts := append(withABSAuth(),
+		mcp.WithDescription("Get today's server log as plain text (admin only)"),
+		readOnlyTool(),
+		mcp.WithNumber("lines", mcp.Description("Only return the last N lines (default: all)")),
+	)
+	serverLogsTool := mcp.NewTool("server_logs", serverLogsOpts...)
+
 	// Backups tools
-	backupsOpts := append(withABSAuth(), mcp.WithDescription("List all server backups"))
+	backupsOpts := append(withABSAuth(), mcp.WithDescription("List all server backups"), readOnlyTool())
 	backupsTool := mcp.NewTool("backups", backupsOpts...)
 
 	// Filesystem tools
-	filesystemOpts := append(withABSAuth(), mcp.WithDescription("List available filesystem paths"))
+	filesystemOpts := append(withABSAuth(), mcp.WithDescription("List available filesystem paths"), readOnlyTool())
 	filesystemTool := mcp.NewTool("filesystem", filesystemOpts...)
 
 	// Authorize tools
-	authorizeOpts := append(withABSAuth(), mcp.WithDescription("Get authorized user and server information"))
+	authorizeOpts := append(withABSAuth(), mcp.WithDescription("Get authorized user and server information"), readOnlyTool())
 	authorizeTool := mcp.NewTool("authorize", authorizeOpts...)
 
 	// Tags and Genres tools
-	tagsOpts := append(withABSAuth(), mcp.WithDescription("Get all library tags"))
+	tagsOpts := append(withABSAuth(), mcp.WithDescription("Get all library tags"), readOnlyTool())
 	tagsTool := mcp.NewTool("tags", tagsOpts...)
 
-	genresOpts := append(withABSAuth(), mcp.WithDescription("Get all available genres"))
+	genresOpts := append(withABSAuth(),
+		mcp.WithDescription("Get all available genres"),
+		readOnlyTool(),
+		mcp.WithString("output", mcp.Description("Response format: json (default) or csv"), mcp.Enum("json", "csv")),
+	)
 	genresTool := mcp.NewTool("genres", genresOpts...)
 
 	// Add ABS Libraries handlers
-	s.AddTool(librariesTool, createSimpleGETHandler("/libraries"))
-	s.AddTool(libraryTool, createGETByIDWithSubResourceHandler("/libraries/%s", "library_id", []string{
+	s.AddTool(librariesTool, withCSVOutput("libraries", withStructuredResult(withSummary("libraries", []string{"id", "name", "mediaType"}, createSimpleGETHandler("/libraries")))))
+	s.AddTool(libraryTool, withStructuredResult(createGETByIDWithSubResourceQueryHandler("/libraries/%s", "library_id", []string{
 		"items",
 		"authors",
 		"series",
@@ -469,7 +5170,14 @@ func main() {
 		"search",
 		"episode-downloads",
 		"recent-episodes",
-	}))
+	}, map[string]queryBuilder{
+		"items":           libraryItemsQuery,
+		"search":          librarySearchQuery,
+		"recent-episodes": libraryRecentEpisodesQuery,
+		"personalized":    libraryPersonalizedQuery,
+	})))
+	s.AddTool(libraryIssuesTool, createGETByIDQueryHandler("/libraries/%s/issues", "library_id", limitPageQuery))
+	s.AddTool(removeLibraryIssuesTool, createConfirmedDELETEByIDHandler("/libraries/%s/issues", "library_id"))
 	s.AddTool(createLibraryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		baseURL, token, err := getABSConfig(request)
 		if err != nil {
@@ -522,52 +5230,41 @@ func main() {
 
 		return mcp.NewToolResultText(string(body)), nil
 	})
+	s.AddTool(scanLibraryTool, handleScanLibrary)
+	s.AddTool(uploadTool, handleUpload)
 
 	// Add ABS Items handlers
 	s.AddTool(itemTool, createGETByIDWithSubResourceHandler("/items/%s", "item_id", []string{
 		"cover",
 		"tone-object",
 	}))
+	s.AddTool(getItemChaptersTool, handleGetItemChapters)
+	s.AddTool(updateItemChaptersTool, handleUpdateItemChapters)
+	s.AddTool(embedMetadataTool, handleEmbedMetadata)
+	s.AddTool(downloadItemFileTool, handleDownloadItemFile)
+	s.AddTool(ebookFileDownloadTool, handleEbookFileDownload)
+	s.AddTool(updateItemCoverTool, handleUpdateItemCover)
+	s.AddTool(deleteItemCoverTool, createDELETEByIDHandler("/items/%s/cover", "item_id"))
+	s.AddTool(matchItemTool, handleMatchItem)
+	s.AddTool(updateItemMediaTool, handleUpdateItemMedia)
+	s.AddTool(deleteItemTool, handleDeleteItem)
+	s.AddTool(batchDeleteItemsTool, handleBatchDeleteItems)
+	s.AddTool(batchGetItemsTool, handleBatchGetItems)
 
 	// Add ABS Authors handlers
 	s.AddTool(authorTool, createGETByIDHandler("/authors/%s", "author_id"))
+	s.AddTool(searchAuthorsTool, handleSearchAuthors)
+	s.AddTool(updateAuthorTool, handleUpdateAuthor)
+	s.AddTool(deleteAuthorTool, createConfirmedDELETEByIDHandler("/authors/%s", "author_id"))
 
 	// Add ABS Me handler
-	s.AddTool(meTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		baseURL, token, err := getABSConfig(request)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-
-		path := "/me"
-
-		// Check for simple boolean sub-resources first
-		if request.GetBool("listening-sessions", false) {
-			path = "/me/listening-sessions"
-		} else if request.GetBool("listening-stats", false) {
-			path = "/me/listening-stats"
-		} else if request.GetBool("items-in-progress", false) {
-			path = "/me/items-in-progress"
-		} else if progressItemID := request.GetString("progress_item_id", ""); progressItemID != "" {
-			// Handle progress endpoints with IDs
-			if progressEpisodeID := request.GetString("progress_episode_id", ""); progressEpisodeID != "" {
-				path = fmt.Sprintf("/me/progress/%s/%s", progressItemID, progressEpisodeID)
-			} else {
-				path = fmt.Sprintf("/me/progress/%s", progressItemID)
-			}
-		}
-
-		body, err := absGET(ctx, baseURL, token, path)
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-
-		return mcp.NewToolResultText(string(body)), nil
-	})
+	s.AddTool(meTool, withHumanizedDurations(handleMe))
+	s.AddTool(meYearReviewTool, handleMeYearReview)
 
 	// Add ABS Sessions handlers
-	s.AddTool(sessionsTool, createSimpleGETHandler("/sessions"))
+	s.AddTool(sessionsTool, withSummary("sessions", []string{"id", "displayTitle"}, createSimpleGETQueryHandler("/sessions", paginationQuery(10))))
 	s.AddTool(sessionTool, createGETByIDHandler("/sessions/%s", "session_id"))
+	s.AddTool(syncSessionTool, handleSyncSession)
 
 	// Add ABS Podcasts handlers
 	s.AddTool(podcastsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -620,9 +5317,16 @@ func main() {
 
 		return mcp.NewToolResultText(string(body)), nil
 	})
+	s.AddTool(createPodcastTool, handleCreatePodcast)
+	s.AddTool(downloadPodcastEpisodesTool, handleDownloadPodcastEpisodes)
+	s.AddTool(deletePodcastEpisodeTool, handleDeletePodcastEpisode)
+	s.AddTool(updatePodcastEpisodeTool, handleUpdatePodcastEpisode)
+	s.AddTool(clearPodcastQueueTool, createPOSTByIDHandler("/podcasts/%s/clear-queue", "podcast_id"))
+	s.AddTool(searchPodcastFeedTool, handleSearchPodcastFeed)
+	s.AddTool(searchPodcastsTool, handleSearchPodcasts)
 
 	// Add ABS Collections handlers
-	s.AddTool(collectionsTool, createSimpleGETHandler("/collections"))
+	s.AddTool(collectionsTool, withSummary("collections", []string{"id", "name"}, createSimpleGETHandler("/collections")))
 	s.AddTool(collectionTool, createGETByIDHandler("/collections/%s", "collection_id"))
 	s.AddTool(createCollectionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		baseURL, token, err := getABSConfig(request)
@@ -685,7 +5389,7 @@ func main() {
 	})
 
 	// Add ABS Playlists handlers
-	s.AddTool(playlistsTool, createSimpleGETHandler("/playlists"))
+	s.AddTool(playlistsTool, withSummary("playlists", []string{"id", "name"}, createSimpleGETHandler("/playlists")))
 	s.AddTool(playlistTool, createGETByIDHandler("/playlists/%s", "playlist_id"))
 	s.AddTool(createPlaylistTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		baseURL, token, err := getABSConfig(request)
@@ -787,7 +5491,7 @@ func main() {
 	})
 
 	// Add progress tracking handler
-	s.AddTool(updateProgressTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.AddTool(updateProgressTool, withHumanizedDurations(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		baseURL, token, err := getABSConfig(request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
@@ -826,20 +5530,52 @@ func main() {
 		}
 
 		return mcp.NewToolResultText(string(body)), nil
-	})
+	}))
+	s.AddTool(removeProgressTool, handleRemoveProgress)
+
+	// Add bookmark handlers
+	s.AddTool(createBookmarkTool, handleCreateBookmark)
+	s.AddTool(updateBookmarkTool, handleUpdateBookmark)
+	s.AddTool(removeSeriesFromContinueTool, handleRemoveSeriesFromContinueListening)
+
+	// Add RSS feed handlers
+	s.AddTool(feedsTool, createSimpleGETHandler("/feeds"))
+	s.AddTool(openRSSFeedTool, createOpenFeedHandler("item", "item_id"))
+	s.AddTool(openCollectionFeedTool, createOpenFeedHandler("collection", "collection_id"))
+	s.AddTool(openSeriesFeedTool, createOpenFeedHandler("series", "series_id"))
+	s.AddTool(closeRSSFeedTool, createPOSTByIDHandler("/feeds/%s/close", "feed_id"))
+
+	// Add notification handlers
+	s.AddTool(notificationsTool, createSimpleGETHandler("/notifications"))
+	s.AddTool(updateNotificationTool, handleUpdateNotificationSettings)
+	s.AddTool(testNotificationTool, createPOSTByIDHandler("/notifications/%s/test", "notification_id"))
+	s.AddTool(notificationEventsTool, createSimpleGETHandler("/notificationdata"))
+
+	// Add email handlers
+	s.AddTool(emailSettingsTool, createSimpleGETHandler("/emails/settings"))
+	s.AddTool(updateEmailSettingsTool, handleUpdateEmailSettings)
+	s.AddTool(sendEbookToDeviceTool, handleSendEbookToDevice)
 
 	// Add Server status/health handlers (these are at root level, not /api)
 	s.AddTool(pingTool, createRootGETHandler("/ping"))
 	s.AddTool(healthcheckTool, createRootGETHandler("/healthcheck"))
 	s.AddTool(statusTool, createRootGETHandler("/status"))
+	s.AddTool(loginTool, handleLogin)
+	s.AddTool(versionTool, handleVersion)
+	s.AddTool(clearCacheTool, handleClearCache)
+	s.AddTool(absGetTool, handleABSGet)
+	s.AddTool(absPostTool, handleABSPost)
 
 	// Add Users handlers
-	s.AddTool(usersTool, createSimpleGETHandler("/users"))
+	s.AddTool(usersTool, withCSVOutput("users", createSimpleGETHandler("/users")))
 	s.AddTool(usersOnlineTool, createSimpleGETHandler("/users/online"))
-	s.AddTool(userTool, createGETByIDWithSubResourceHandler("/users/%s", "user_id", []string{
+	s.AddTool(userTool, createGETByIDWithSubResourceQueryHandler("/users/%s", "user_id", []string{
 		"listening-sessions",
 		"listening-stats",
+	}, map[string]queryBuilder{
+		"listening-sessions": paginationQuery(10),
 	}))
+	s.AddTool(updateUserTool, handleUpdateUser)
 
 	// Add Series handler
 	s.AddTool(seriesTool, createGETByIDHandler("/series/%s", "series_id"))
@@ -847,6 +5583,12 @@ func main() {
 	// Add Author image handler
 	s.AddTool(authorImageTool, createGETByIDHandler("/authors/%s/image", "author_id"))
 
+	// Add Server stats handler
+	s.AddTool(serverStatsTool, createSimpleGETHandler("/stats/server"))
+
+	// Add Server logs handler
+	s.AddTool(serverLogsTool, handleServerLogs)
+
 	// Add Backups handler
 	s.AddTool(backupsTool, createSimpleGETHandler("/backups"))
 
@@ -858,10 +5600,38 @@ func main() {
 
 	// Add Tags and Genres handlers
 	s.AddTool(tagsTool, createSimpleGETHandler("/tags"))
-	s.AddTool(genresTool, createSimpleGETHandler("/genres"))
+	s.AddTool(genresTool, withCSVOutput("genres", createSimpleGETHandler("/genres")))
+
+	// Add ABS listing resources
+	for _, listing := range listingResources {
+		resource := mcp.NewResource(listing.uri, listing.name,
+			mcp.WithResourceDescription(listing.description),
+			mcp.WithMIMEType("application/json"),
+		)
+		s.AddResource(resource, createListingResourceHandler(listing.path))
+	}
 
-	// Start the server
-	if err := server.ServeStdio(s); err != nil {
-		fmt.Printf("Server error: %v\n", err)
+	// Add ABS resource templates for single libraries and items
+	for _, entity := range entityResourceTemplates {
+		template := mcp.NewResourceTemplate(entity.uriTemplate, entity.name,
+			mcp.WithTemplateDescription(entity.description),
+			mcp.WithTemplateMIMEType("application/json"),
+		)
+		s.AddResourceTemplate(template, createEntityResourceHandler(entity.pathTemplate, entity.idName))
 	}
+
+	// Add prompts for common listening workflows
+	recommendNextListenPrompt := mcp.NewPrompt("recommend_next_listen",
+		mcp.WithPromptDescription("Recommend what to listen to next based on in-progress items and personalized shelves"),
+		mcp.WithArgument("library_id", mcp.ArgumentDescription("Library to recommend from (default: all libraries)")),
+	)
+	s.AddPrompt(recommendNextListenPrompt, handleRecommendNextListenPrompt)
+
+	librarySummaryPrompt := mcp.NewPrompt("library_summary",
+		mcp.WithPromptDescription("Summarize a library's size, makeup, and top authors and series"),
+		mcp.WithArgument("library_id", mcp.ArgumentDescription("Library to summarize"), mcp.RequiredArgument()),
+	)
+	s.AddPrompt(librarySummaryPrompt, handleLibrarySummaryPrompt)
+
+	return s
 }